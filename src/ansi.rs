@@ -0,0 +1,48 @@
+use once_cell::sync::Lazy;
+use regex::Regex;
+use unicode_width::UnicodeWidthStr;
+
+static ANSI_ESCAPE_RE: Lazy<Regex> =
+    Lazy::new(|| Regex::new(r"\x1b\[[0-9;]*[A-Za-z]").expect("valid ansi regex"));
+
+pub fn strip_ansi(input: &str) -> String {
+    ANSI_ESCAPE_RE.replace_all(input, "").to_string()
+}
+
+// Terminal columns occupied by `input`, ignoring escape sequences.
+pub fn visible_width(input: &str) -> usize {
+    UnicodeWidthStr::width(strip_ansi(input).as_str())
+}
+
+// Cut `input` to at most `width` visible columns, keeping escape sequences intact.
+pub fn truncate_visible(input: &str, width: usize) -> String {
+    if visible_width(input) <= width {
+        return input.to_string();
+    }
+    let mut out = String::new();
+    let mut used = 0;
+    let mut last = 0;
+    for m in ANSI_ESCAPE_RE.find_iter(input) {
+        if !push_visible(&mut out, &input[last..m.start()], width, &mut used) {
+            break;
+        }
+        out.push_str(m.as_str());
+        last = m.end();
+    }
+    push_visible(&mut out, &input[last..], width, &mut used);
+    out.push_str("\x1b[0m");
+    out
+}
+
+// Append characters until the width budget runs out; false once it has.
+fn push_visible(out: &mut String, text: &str, width: usize, used: &mut usize) -> bool {
+    for ch in text.chars() {
+        let cw = UnicodeWidthStr::width(ch.to_string().as_str());
+        if *used + cw > width {
+            return false;
+        }
+        out.push(ch);
+        *used += cw;
+    }
+    true
+}
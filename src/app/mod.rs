@@ -1,61 +1,115 @@
+use std::cell::RefCell;
+use std::collections::{BTreeSet, HashMap};
 use std::io::{self, Write};
 use std::path::PathBuf;
 use std::time::{Duration, Instant, SystemTime};
 
 use crossterm::cursor::{Hide, MoveTo, Show};
-use crossterm::event::{self, Event};
+use crossterm::event::{
+    self, DisableMouseCapture, EnableMouseCapture, Event, MouseButton, MouseEventKind,
+};
 use crossterm::terminal::{
     disable_raw_mode, enable_raw_mode, Clear, ClearType, EnterAlternateScreen, LeaveAlternateScreen,
 };
 use crossterm::ExecutableCommand;
 use log::debug;
 
-use crate::edit::clamp_cursor;
-use crate::external_edit::edit_in_external_editor;
-use crate::io::{load_lines, save_lines};
+use crate::config::{Config, QuitAction, SortKey};
+use crate::date::Date;
+use crate::edit::{clamp_cursor, get_indent_level};
+use crate::external_edit::{edit_file_in_external_editor, edit_in_external_editor};
+use crate::fold::carry_over_folds;
+use crate::io::{
+    detect_indent_unit, line_ending_of, load_path, matches_disk, modified_time, parse_lines,
+    save_path, serialize_lines, unrecognized_lines, LineEnding,
+};
 use crate::keys::{map_key, Key};
+use crate::metadata::due;
 use crate::model::{
-    App, EditIntent, EditTarget, LineItem, Mode, Task, UndoState, MAX_UNDO_HISTORY,
+    App, Confirm, EditIntent, EditTarget, Filter, LineItem, Mode, Overlay, Task, UndoState,
+    MAX_UNDO_HISTORY,
 };
+use crate::render::match_ranges;
+use crate::state::{load_cursor, save_cursor};
 use crate::text_input::TextInput;
 
 const FILE_CHECK_INTERVAL: Duration = Duration::from_secs(1);
 const DEFAULT_WINDOW_WIDTH: u16 = 80;
 
 impl App {
-    pub fn new(path: PathBuf) -> Result<Self, String> {
-        let (lines, mod_time) = load_lines(&path).map_err(|e| e.to_string())?;
+    pub fn new(path: PathBuf, config: Config) -> Result<Self, String> {
+        let (lines, mod_time) = load_path(&path).map_err(|e| e.to_string())?;
         let template = default_task_template(&lines);
+        let line_ending = line_ending_of(&path);
+        let indent_unit = detect_indent_unit(&lines).unwrap_or_else(|| config.format.indent_unit());
 
-        Ok(Self {
+        let mut app = Self {
             file_path: path,
             lines,
             cursor: 0,
             mode: Mode::Normal,
             text_input: TextInput::new(),
             search_input: TextInput::new(),
+            command_input: TextInput::new(),
             input_placeholder: "Describe the task".to_string(),
             edit_intent: EditIntent::None,
             edit_target: EditTarget::Task,
             edit_index: None,
             insert_index: None,
             edit_template: template,
+            edit_wrap: false,
             status_message: String::new(),
+            status_seq: 0,
             error: None,
             last_modified: mod_time,
             pending_reload: false,
+            dirty: false,
             selection_active: false,
             selection_anchor: 0,
+            marked: BTreeSet::new(),
             window_width: DEFAULT_WINDOW_WIDTH,
             window_height: 0,
             renderer_width: 0,
+            markdown_cache: RefCell::new(HashMap::new()),
             external_edit_idx: None,
             undo_stack: Vec::new(),
             redo_stack: Vec::new(),
-            pending_d: false,
+            register: Vec::new(),
+            pending_key: None,
+            pending_confirm: None,
+            pending_count: None,
             scroll_offset: 0,
+            overlay_scroll: 0,
             should_quit: false,
-        })
+            outline: false,
+            relative_dates: config.display.relative_dates,
+            markdown: config.display.markdown,
+            date_picker: None,
+            preview: None,
+            split_view: false,
+            show_comments: false,
+            filter: Filter::All,
+            indent_unit,
+            line_ending,
+            tag_filter: None,
+            tag_cursor: 0,
+            screen_rows: Vec::new(),
+            last_edit: None,
+            config,
+            stashed_buffer: None,
+            inbox_active: false,
+            tabs: Vec::new(),
+            active_tab: 0,
+        };
+        app.restore_fold_state();
+        // The file may have shrunk since the cursor was saved.
+        if let Some(cursor) = load_cursor(&app.file_path) {
+            app.cursor = clamp_cursor(cursor, app.lines.len());
+            app.clamp_cursor_to_visible();
+        }
+        app.report_unrecognized_lines();
+        app.auto_archive(Date::today());
+        Ok(app)
     }
 
     pub fn run(mut self) -> Result<(), String> {
@@ -66,6 +120,8 @@ impl App {
 
         let mut last_file_check = Instant::now();
         let mut dirty = false;
+        // The status text as of the last pass and when it first appeared.
+        let mut shown_status = (self.status_seq, Instant::now());
 
         // Main loop: poll for input, check file changes, and re-render on updates.
         while !self.should_quit {
@@ -87,6 +143,12 @@ impl App {
                         self.handle_key(key);
                         dirty = true;
                     }
+                    Event::Mouse(mouse) => {
+                        if let MouseEventKind::Down(MouseButton::Left) = mouse.kind {
+                            self.handle_click(mouse.column as usize, mouse.row as usize);
+                            dirty = true;
+                        }
+                    }
                     Event::Resize(w, h) => {
                         self.window_width = w;
                         self.window_height = h;
@@ -97,12 +159,19 @@ impl App {
                 }
             }
 
+            if self.expire_status(&mut shown_status, Instant::now()) {
+                dirty = true;
+            }
             if dirty {
                 self.render_to_terminal()?;
                 dirty = false;
             }
         }
 
+        self.save_fold_state();
+        if let Err(err) = save_cursor(&self.file_path, self.cursor) {
+            debug!("failed to save cursor: {}", err);
+        }
         Ok(())
     }
 
@@ -133,20 +202,120 @@ impl App {
             Mode::Edit => self.handle_edit_key(key),
             Mode::Normal => self.handle_normal_key(key),
             Mode::Search => self.handle_search_key(key),
+            Mode::Command => self.handle_command_key(key),
+            Mode::Overlay(_) => self.handle_overlay_key(key),
         }
     }
 
     fn handle_normal_key(&mut self, key: Key) {
-        if self.pending_d {
-            self.pending_d = false;
-            if key == Key::Char('d') {
-                self.delete_current_line();
+        if key == Key::Ctrl('g') {
+            self.reset_interaction_state();
+            return;
+        }
+        if let Some(confirm) = self.pending_confirm.take() {
+            self.handle_confirm_key(confirm, key);
+            return;
+        }
+        // Apply `[keys]` remaps. After a prefix key only a repeat of it is
+        // remapped (so a rebound `dd` still works); `yc`, `cr`, ... keep
+        // their second key.
+        let key = match key {
+            Key::Char(c) => match (self.config.keys.translate(c), self.pending_key) {
+                (Some(builtin), Some(p)) if builtin == p => Key::Char(builtin),
+                (_, Some(_)) => key,
+                (Some(builtin), None) => Key::Char(builtin),
+                (None, None) => return,
+            },
+            _ => key,
+        };
+
+        if let Key::Char(c @ '0'..='9') = key {
+            // A leading 0 isn't a count; digits after a prefix key aren't either.
+            if (c != '0' || self.pending_count.is_some()) && self.pending_key.is_none() {
+                let digit = c.to_digit(10).unwrap_or(0) as usize;
+                let count = self
+                    .pending_count
+                    .unwrap_or(0)
+                    .saturating_mul(10)
+                    .saturating_add(digit);
+                self.pending_count = Some(count);
+                self.set_status(count.to_string());
                 return;
             }
         }
+        let count = self.pending_count.take();
+
+        if let Some(prefix) = self.pending_key.take() {
+            match (prefix, key) {
+                ('d', Key::Char('d')) => {
+                    self.delete_current_line(count.unwrap_or(1));
+                    return;
+                }
+                ('y', Key::Char('y')) => {
+                    self.yank_lines(count.unwrap_or(1));
+                    return;
+                }
+                ('y', Key::Char('c')) => {
+                    self.copy_task_text();
+                    return;
+                }
+                ('y', Key::Char('m')) => {
+                    self.copy_task_markdown();
+                    return;
+                }
+                ('y', Key::Char('r')) => {
+                    self.copy_task_reference();
+                    return;
+                }
+                ('c', Key::Char(c)) => {
+                    self.set_task_color(c);
+                    return;
+                }
+                ('g', Key::Char('g')) => {
+                    self.move_cursor_to_visible_first();
+                    return;
+                }
+                ('g', Key::Char('t')) => {
+                    match count {
+                        Some(n) => self.switch_tab(n.saturating_sub(1)),
+                        None => self.cycle_tab(1),
+                    }
+                    return;
+                }
+                ('g', Key::Char('J')) => {
+                    self.join_next_task();
+                    return;
+                }
+                ('g', Key::Char('T')) => {
+                    self.cycle_tab(-(count.unwrap_or(1) as isize));
+                    return;
+                }
+                ('`', Key::Char('`')) => {
+                    self.jump_to_last_edit();
+                    return;
+                }
+                ('z', Key::Char('a')) => {
+                    self.toggle_fold();
+                    return;
+                }
+                ('>', Key::Char('>')) => {
+                    self.shift_indent(1);
+                    return;
+                }
+                ('<', Key::Char('<')) => {
+                    self.shift_indent(-1);
+                    return;
+                }
+                ('>', Key::Char(c @ '1'..='9')) => {
+                    self.move_task_to_section(c.to_digit(10).unwrap_or(0) as usize);
+                    return;
+                }
+                // Any other key cancels the prefix and runs as usual.
+                _ => self.status_message.clear(),
+            }
+        }
 
         if key == Key::Char('/') {
-            self.pending_d = false;
             self.clear_selection();
             self.mode = Mode::Search;
             if self.search_active() {
@@ -154,7 +323,7 @@ impl App {
             } else {
                 self.search_input.reset();
             }
-            self.ensure_cursor_visible_for_search();
+            self.clamp_cursor_to_visible();
             return;
         }
 
@@ -164,26 +333,90 @@ impl App {
                 self.search_input.reset();
                 cleared = true;
             }
-            if self.selection_active {
-                self.selection_active = false;
-                self.status_message = "Selection canceled".to_string();
+            if self.selection_active || !self.marked.is_empty() {
+                self.clear_selection();
+                self.set_status("Selection canceled");
+            } else if !cleared && self.tag_filter.is_some() {
+                self.set_tag_filter(None);
             }
             if cleared {
-                self.status_message = "Search cleared".to_string();
+                self.set_status("Search cleared");
             }
             return;
         }
 
         match key {
-            Key::Ctrl('c') => self.should_quit = true,
-            Key::Char('q') => self.should_quit = true,
-            Key::Char('j') | Key::Down => self.move_cursor_visible(1),
-            Key::Char('k') | Key::Up => self.move_cursor_visible(-1),
-            Key::Char('g') => self.move_cursor_to_visible_first(),
+            Key::Ctrl('c') | Key::Char('q') if self.has_unsaved_changes() => {
+                self.request_confirm(Confirm::Quit)
+            }
+            Key::Ctrl('c') | Key::Char('q') => self.should_quit = true,
+            Key::Char('j') | Key::Down => self.move_cursor_visible(count.unwrap_or(1) as isize),
+            Key::Char('k') | Key::Up => self.move_cursor_visible(-(count.unwrap_or(1) as isize)),
+            // `g` waits for `g` (first line), `J` (join) or `t`/`T` (tabs).
+            Key::Char('g') => {
+                self.pending_key = Some('g');
+                self.pending_count = count;
+                let prefix = count.map(|n| n.to_string()).unwrap_or_default();
+                self.set_status(format!("{}g-", prefix));
+            }
+            // `10G` goes to line 10 of the file (or the nearest visible line).
+            Key::Char('G') if count.is_some() => self.move_cursor_to_line(count.unwrap_or(1)),
             Key::Char('G') => self.move_cursor_to_visible_last(),
+            Key::Char('n') => self.jump_to_match(true),
+            Key::Char('N') => self.jump_to_match(false),
+            Key::Char(']') | Key::Char('}') => self.jump_to_section(true),
+            Key::Char('[') | Key::Char('{') => self.jump_to_section(false),
+            Key::Ctrl('n') => self.move_cursor_to_incomplete(true),
+            Key::Ctrl('p') => self.move_cursor_to_incomplete(false),
+            Key::Ctrl('d') => {
+                self.relative_dates = !self.relative_dates;
+                self.set_status(if self.relative_dates {
+                    "Relative due dates"
+                } else {
+                    "Absolute due dates"
+                });
+            }
+            Key::Ctrl('t') => {
+                self.markdown = !self.markdown;
+                self.set_status(if self.markdown {
+                    "Markdown styling on"
+                } else {
+                    "Plain task text"
+                });
+            }
+            // With a selection, one `d` (or `x`) cuts it; no `dd` needed.
+            Key::Char('d') | Key::Char('x') if self.has_selection() => self.delete_selected(),
             Key::Char('d') => {
-                self.pending_d = true;
-                self.status_message = "d-".to_string();
+                self.pending_key = Some('d');
+                self.pending_count = count;
+                let prefix = count.map(|n| n.to_string()).unwrap_or_default();
+                self.set_status(format!("{}d-", prefix));
+            }
+            Key::Char('y') => {
+                self.pending_key = Some('y');
+                self.pending_count = count;
+                let prefix = count.map(|n| n.to_string()).unwrap_or_default();
+                self.set_status(format!("{}y-", prefix));
+            }
+            Key::Char('c') => {
+                self.pending_key = Some('c');
+                self.set_status(self.color_prompt());
+            }
+            Key::Char('`') => {
+                self.pending_key = Some('`');
+                self.set_status("`-");
+            }
+            Key::Char('z') => {
+                self.pending_key = Some('z');
+                self.set_status("z-");
+            }
+            Key::Char('>') => {
+                self.pending_key = Some('>');
+                self.set_status(format!("> indent · {}", self.section_move_prompt()));
+            }
+            Key::Char('<') => {
+                self.pending_key = Some('<');
+                self.set_status("<-");
             }
             Key::Char('u') => {
                 self.undo();
@@ -197,10 +430,25 @@ impl App {
                     self.save_and_set_status("Redo");
                 }
             }
+            Key::Enter if self.expand_current_section() => {}
             Key::Enter | Key::Char(' ') => self.toggle_tasks(),
+            Key::Char('t') => self.triage_task(),
+            Key::Char('X') => self.toggle_and_file_task(),
+            Key::Char('A') => self.toggle_subtasks(),
+            Key::Char('J') | Key::Ctrl('j') => self.move_line(1),
+            Key::Char('K') | Key::Ctrl('k') => self.move_line(-1),
+            Key::Char('#') => self.comment_out_task(),
+            Key::Char('+') => self.uncomment_task(),
+            Key::Char('H') => self.toggle_comments(),
+            Key::Char('f') => self.cycle_filter(),
+            Key::Char('|') => self.toggle_split_view(),
+            Key::Char('h') if self.split_view => self.focus_pane(false),
+            Key::Char('l') if self.split_view => self.focus_pane(true),
+            Key::BackTab => self.toggle_outline(),
+            Key::Tab => self.toggle_fold(),
             Key::Char('V') | Key::Char('v') => {
                 if self.search_active() {
-                    self.status_message = "Selection disabled while searching".to_string();
+                    self.set_status("Selection disabled while searching");
                     return;
                 }
                 if self.lines.is_empty() {
@@ -208,46 +456,50 @@ impl App {
                 }
                 if self.selection_active {
                     self.selection_active = false;
-                    self.status_message = "Selection cleared".to_string();
+                    self.set_status("Selection cleared");
                 } else {
                     self.selection_active = true;
                     self.selection_anchor = self.cursor;
-                    self.status_message = "Visual line selection".to_string();
+                    self.set_status("Visual line selection");
                 }
             }
+            Key::Char('m') => self.toggle_mark(),
             Key::Char('e') => {
                 let _ = self.start_external_edit();
             }
+            Key::Char('E') => self.start_external_file_edit(),
             Key::Char('i') => self.start_edit_current(),
-            Key::Char('o') => self.start_insert_task_at(self.cursor + 1),
+            Key::Char('F') => self.open_focus(),
+            Key::Char('%') => self.open_tag_picker(),
+            Key::Char(':') => self.open_command_line(),
+            Key::Char('?') => self.open_overlay(Overlay::Help),
+            Key::Char('@') => self.open_date_picker(),
+            Key::Char('o') => self.start_insert_task_at(self.insert_below_index()),
             Key::Char('O') => self.start_insert_task_at(self.cursor),
             Key::Char('S') => self.start_insert_section_at(self.cursor + 1),
-            Key::Char('r') => match load_lines(&self.file_path) {
-                Ok((lines, mod_time)) => {
-                    self.lines = lines;
-                    self.cursor = clamp_cursor(self.cursor, self.lines.len());
-                    self.normalize_selection();
-                    self.last_modified = mod_time;
-                    self.edit_template = default_task_template(&self.lines);
-                    self.status_message = "Reloaded".to_string();
-                    self.error = None;
-                }
-                Err(err) => self.error = Some(err.to_string()),
-            },
+            Key::Char('T') => self.open_daily_log(),
+            Key::Char('-') => self.insert_rule(),
+            Key::Char('p') => self.paste(true),
+            Key::Char('P') => self.paste(false),
+            Key::Char('Y') => self.duplicate_lines(),
+            Key::Char('I') => self.toggle_inbox(),
+            Key::Char('M') => self.move_to_other_buffer(),
+            Key::Char('=') => self.format_document(),
+            Key::Char('B') => self.normalize_bullets(),
+            Key::Char('s') => self.sort_section(SortKey::Priority),
+            Key::Char('D') => self.sort_section(SortKey::Incomplete),
+            Key::Char('~') => self.request_confirm(Confirm::InvertAll),
+            Key::Char('C') => self.request_confirm(Confirm::ResetAll),
+            Key::Char('r') if self.dirty => self.request_confirm(Confirm::Reload),
+            Key::Char('r') => self.reload("Reloaded", false),
+            Key::Char('R') => self.reload("Reloaded from disk, history cleared", true),
             _ => {}
         }
     }
 
     fn handle_edit_key(&mut self, key: Key) {
         match key {
-            Key::Esc => {
-                let raw = self.text_input.value().to_string();
-                if !raw.trim().is_empty() {
-                    self.apply_current_edit(&raw);
-                    self.save_and_set_status("Saved");
-                }
-                self.exit_edit_mode();
-            }
+            Key::Esc => self.finish_edit(),
             Key::Enter => {
                 let raw = self.text_input.value().to_string();
                 if raw.trim().is_empty() {
@@ -271,6 +523,19 @@ impl App {
 
                 self.exit_edit_mode();
             }
+            Key::Ctrl('c') | Key::Ctrl('q') => match self.config.edit.on_quit {
+                QuitAction::Save => {
+                    self.finish_edit();
+                    self.should_quit = true;
+                }
+                QuitAction::Discard => {
+                    self.exit_edit_mode();
+                    self.should_quit = true;
+                }
+                QuitAction::Ignore => {
+                    self.set_status("Press Esc to leave edit mode before quitting");
+                }
+            },
             Key::Tab => {
                 if self.edit_target == EditTarget::Task {
                     self.change_indent(1);
@@ -281,6 +546,15 @@ impl App {
                     self.change_indent(-1);
                 }
             }
+            Key::Ctrl('j') => self.split_edited_task(),
+            Key::Ctrl('w') => {
+                self.edit_wrap = !self.edit_wrap;
+                self.set_status(if self.edit_wrap {
+                    "Wrapped editing"
+                } else {
+                    "Single-line editing"
+                });
+            }
             Key::Char(c) => self.text_input.insert_char(c),
             Key::Backspace => self.text_input.backspace(),
             Key::Delete => self.text_input.delete(),
@@ -292,36 +566,54 @@ impl App {
         }
     }
 
+    // Save a non-empty edit and leave edit mode; an empty one is dropped.
+    fn finish_edit(&mut self) {
+        let raw = self.text_input.value().to_string();
+        if !raw.trim().is_empty() {
+            self.apply_current_edit(&raw);
+            self.save_and_set_status("Saved");
+        }
+        self.exit_edit_mode();
+    }
+
     fn handle_search_key(&mut self, key: Key) {
         match key {
+            Key::Ctrl('g') => self.reset_interaction_state(),
             Key::Esc => {
                 self.search_input.reset();
                 self.mode = Mode::Normal;
-                self.status_message = "Search cleared".to_string();
+                self.set_status("Search cleared");
                 self.clear_selection();
             }
             Key::Enter => {
                 self.mode = Mode::Normal;
-                self.toggle_tasks();
+                let matches = self.search_matches();
+                match matches.first() {
+                    Some(&first) => {
+                        self.cursor = first;
+                        self.set_status(format!("Match 1 of {}", matches.len()));
+                    }
+                    None => self.set_status("No matches"),
+                }
             }
             Key::Char(c) => {
                 self.search_input.insert_char(c);
-                self.ensure_cursor_visible_for_search();
+                self.clamp_cursor_to_visible();
             }
             Key::Backspace => {
                 let remaining = self.search_input.value().chars().count();
                 if remaining <= 1 {
                     self.search_input.reset();
                     self.mode = Mode::Normal;
-                    self.status_message = "Search cleared".to_string();
+                    self.set_status("Search cleared");
                     return;
                 }
                 self.search_input.backspace();
-                self.ensure_cursor_visible_for_search();
+                self.clamp_cursor_to_visible();
             }
             Key::Delete => {
                 self.search_input.delete();
-                self.ensure_cursor_visible_for_search();
+                self.clamp_cursor_to_visible();
             }
             Key::Left => self.search_input.move_left(),
             Key::Right => self.search_input.move_right(),
@@ -333,43 +625,46 @@ impl App {
         }
     }
 
-    fn toggle_tasks(&mut self) {
+    pub(crate) fn toggle_tasks(&mut self) {
         if self.lines.is_empty() {
             return;
         }
         if self.search_active() && self.mode != Mode::Edit {
             let indices = self.visible_indices();
             if indices.is_empty() {
-                self.status_message = "No matches".to_string();
+                self.set_status("No matches");
                 return;
             }
-            if !indices.contains(&self.cursor) {
-                self.ensure_cursor_visible_in(&indices);
-            }
+            self.clamp_cursor_to_visible();
+        }
+        let targets: Vec<usize> = if self.has_selection() {
+            self.selected_indices()
+        } else {
+            vec![self.cursor]
+        };
+        let targets: Vec<usize> = targets
+            .into_iter()
+            .filter(|&i| self.lines.get(i).is_some_and(LineItem::is_task))
+            .collect();
+        if targets.is_empty() {
+            return;
         }
-        let mut count = 0;
-        let mut last_toggled: Option<bool> = None;
 
-        if self.selection_active {
-            if let Some((start, end)) = self.selection_range() {
-                for i in start..=end {
-                    if let Some(LineItem::Task(task)) = self.lines.get_mut(i) {
-                        task.completed = !task.completed;
-                        count += 1;
-                        last_toggled = Some(task.completed);
-                    }
-                }
+        // One undo step for the whole selection.
+        self.save_undo_state();
+        self.clear_selection();
+        let mut last_toggled: Option<bool> = None;
+        let mark = self.config.format.done_mark;
+        let stamp = self.done_stamp();
+        for &i in &targets {
+            if let Some(LineItem::Task(task)) = self.lines.get_mut(i) {
+                task.set_completed(!task.completed, mark, stamp.as_deref());
+                last_toggled = Some(task.completed);
             }
-            self.selection_active = false;
-        } else if let Some(LineItem::Task(task)) = self.lines.get_mut(self.cursor) {
-            task.completed = !task.completed;
-            count = 1;
-            last_toggled = Some(task.completed);
+            self.remember_edit(i);
         }
 
-        if count == 0 {
-            return;
-        }
+        let count = targets.len();
         if count == 1 {
             let state = if last_toggled.unwrap_or(false) {
                 "Completed"
@@ -382,6 +677,36 @@ impl App {
         }
     }
 
+    // Triage: flip the current task, then advance to the next visible task.
+    // Only two states exist, so the cycle is incomplete <-> complete. Each
+    // step is its own undo entry.
+    fn triage_task(&mut self) {
+        let completed = match self.lines.get(self.cursor) {
+            Some(LineItem::Task(task)) => !task.completed,
+            _ => {
+                self.set_status("No task to triage");
+                return;
+            }
+        };
+        self.save_undo_state();
+        self.clear_selection();
+        let mark = self.config.format.done_mark;
+        let stamp = self.done_stamp();
+        if let Some(LineItem::Task(task)) = self.lines.get_mut(self.cursor) {
+            task.set_completed(completed, mark, stamp.as_deref());
+        }
+        self.remember_edit(self.cursor);
+        self.save_and_set_status(if completed { "Done" } else { "Open" });
+
+        let next = self
+            .visible_indices()
+            .into_iter()
+            .find(|&i| i > self.cursor && self.lines[i].is_task());
+        if let Some(next) = next {
+            self.cursor = next;
+        }
+    }
+
     // Run external editor synchronously while suspending the TUI.
     fn start_external_edit(&mut self) -> Result<(), String> {
         if self.lines.is_empty() {
@@ -400,16 +725,17 @@ impl App {
                 if let Some(idx) = self.external_edit_idx {
                     if let Some(LineItem::Task(task)) = self.lines.get_mut(idx) {
                         task.text = new_text;
+                        self.remember_edit(idx);
                         self.save_and_set_status("Saved");
                     }
                 }
             }
             Ok(None) => {
-                self.status_message = "Cannot save empty task".to_string();
+                self.set_status("Cannot save empty task");
             }
             Err(err) => {
                 self.error = Some(err);
-                self.status_message = "Editor error".to_string();
+                self.set_status("Editor error");
             }
         }
 
@@ -417,65 +743,215 @@ impl App {
         Ok(())
     }
 
-    fn delete_current_line(&mut self) {
+    // `E`: edit the whole file in the external editor and take the result
+    // as one undoable change. Emptying the file leaves the list alone.
+    fn start_external_file_edit(&mut self) {
+        if self.lines.iter().any(LineItem::is_file) {
+            self.set_status("A board can only be edited one task at a time");
+            return;
+        }
+        self.clear_selection();
+        let before = serialize_lines(&self.lines, LineEnding::Lf);
+        let after = match edit_file_in_external_editor(&before) {
+            Ok(after) => after,
+            Err(err) => {
+                self.error = Some(err);
+                self.set_status("Editor error");
+                return;
+            }
+        };
+        if after.trim().is_empty() {
+            self.set_status("Editor left the file empty; nothing changed");
+            return;
+        }
+        if after.replace('\r', "") == before {
+            self.set_status("No changes");
+            return;
+        }
+
+        self.save_undo_state();
+        let mut lines = parse_lines(&after);
+        carry_over_folds(&self.lines, &mut lines);
+        if let Some(unit) = detect_indent_unit(&lines) {
+            self.indent_unit = unit;
+        }
+        self.lines = lines;
+        self.edit_template = default_task_template(&self.lines);
+        self.clamp_cursor_to_visible();
+        self.save_and_set_status("Saved file from editor");
+    }
+
+    fn delete_current_line(&mut self, count: usize) {
         if self.lines.is_empty() {
-            self.status_message = "Nothing to delete".to_string();
+            self.set_status("Nothing to delete");
+            return;
+        }
+        if self.has_selection() {
+            self.delete_selected();
+            return;
+        }
+        // `3dd` takes the lines as shown, skipping folded, collapsed,
+        // filtered and blank lines in between.
+        let indices = self.count_range(count);
+        if indices.iter().any(|&i| self.lines[i].is_file()) {
+            self.set_status("Cannot delete a file header");
             return;
         }
-        if self.lines[self.cursor].is_section() {
+        if indices.len() > 1 {
+            self.delete_lines(&indices);
+        } else if self.lines[self.cursor].is_section() {
             self.delete_current_section();
-        } else {
+        } else if self.lines[self.cursor].is_task() {
             self.delete_current_task();
+        } else {
+            self.delete_lines(&indices);
+        }
+    }
+
+    // The cursor line and the `count - 1` navigable lines after it, for
+    // counted commands like `3dd` and `3yy`.
+    pub(crate) fn count_range(&self, count: usize) -> Vec<usize> {
+        let navigable = self.navigable_indices();
+        match navigable.iter().position(|&i| i == self.cursor) {
+            Some(pos) => navigable[pos..]
+                .iter()
+                .take(count.max(1))
+                .copied()
+                .collect(),
+            None => vec![self.cursor],
+        }
+    }
+
+    // Delete the lines at `indices` (in order) as a single undoable step,
+    // keeping them in the register.
+    fn delete_lines(&mut self, indices: &[usize]) {
+        self.save_undo_state();
+        self.clear_selection();
+        self.register = indices.iter().map(|&i| self.lines[i].clone()).collect();
+        for &i in indices.iter().rev() {
+            self.lines.remove(i);
         }
+        self.cursor = indices[0];
+        self.clamp_cursor_to_visible();
+        let msg = if indices.len() == 1 {
+            "Deleted 1 line".to_string()
+        } else {
+            format!("Deleted {} lines", indices.len())
+        };
+        self.save_and_set_status(&msg);
+    }
+
+    // Delete every selected line (file headers excepted) as one undoable step.
+    fn delete_selected(&mut self) {
+        let indices: Vec<usize> = self
+            .selected_indices()
+            .into_iter()
+            .filter(|&i| !self.lines[i].is_file())
+            .collect();
+        if indices.is_empty() {
+            self.set_status("Nothing to delete");
+            return;
+        }
+        self.save_undo_state();
+        self.clear_selection();
+        self.register = indices.iter().map(|&i| self.lines[i].clone()).collect();
+        for &i in indices.iter().rev() {
+            self.lines.remove(i);
+        }
+        self.cursor = indices[0];
+        self.clamp_cursor_to_visible();
+        self.save_and_set_status(&format!("Deleted {} lines", indices.len()));
     }
 
     fn delete_current_task(&mut self) {
         if self.lines.is_empty() || !self.lines[self.cursor].is_task() {
-            self.status_message = "No task to delete".to_string();
+            self.set_status("No task to delete");
             return;
         }
         self.save_undo_state();
         self.clear_selection();
-        self.lines.remove(self.cursor);
-        self.cursor = clamp_cursor(self.cursor, self.lines.len());
+        self.register = vec![self.lines.remove(self.cursor)];
+        self.clamp_cursor_to_visible();
         self.save_and_set_status("Deleted task");
     }
 
     fn delete_current_section(&mut self) {
         if self.lines.is_empty() || !self.lines[self.cursor].is_section() {
-            self.status_message = "No section to delete".to_string();
+            self.set_status("No section to delete");
             return;
         }
         self.save_undo_state();
         self.clear_selection();
-        self.lines.remove(self.cursor);
-        self.cursor = clamp_cursor(self.cursor.saturating_sub(1), self.lines.len());
+        self.register = vec![self.lines.remove(self.cursor)];
+        self.cursor = self.cursor.saturating_sub(1);
+        self.clamp_cursor_to_visible();
         self.save_and_set_status("Deleted section");
     }
 
-    fn save_and_set_status(&mut self, msg: &str) {
-        match save_lines(&self.file_path, &self.lines) {
+    pub(crate) fn save_and_set_status(&mut self, msg: &str) {
+        self.apply_sort_preference();
+        let warning = match self.backup_before_data_loss() {
+            Ok(warning) => warning,
+            Err(err) => {
+                self.dirty = true;
+                self.error = Some(err);
+                return;
+            }
+        };
+        match save_path(&self.file_path, &self.lines, self.line_ending) {
             Ok(mod_time) => {
+                self.dirty = false;
                 self.last_modified = mod_time;
-                self.status_message = msg.to_string();
+                self.set_status(match warning {
+                    Some(warning) => format!("{} · {}", warning, msg),
+                    None => msg.to_string(),
+                });
                 self.error = None;
             }
-            Err(err) => self.error = Some(err.to_string()),
+            Err(err) => {
+                self.dirty = true;
+                self.error = Some(err.to_string());
+            }
         }
     }
 
+    pub(crate) fn set_status(&mut self, msg: impl Into<String>) {
+        self.status_message = msg.into();
+        self.status_seq += 1;
+    }
+
+    // Clear the status message once it has shown for `[display]
+    // status_timeout` seconds. `shown` holds the status_seq last seen and
+    // when it appeared; any new message, even one repeating the last,
+    // restarts the clock. Errors, prompts and half-typed commands stay put.
+    // Returns whether the message was cleared.
+    fn expire_status(&mut self, shown: &mut (u64, Instant), now: Instant) -> bool {
+        if self.status_seq != shown.0 {
+            *shown = (self.status_seq, now);
+            return false;
+        }
+        let timeout = self.config.display.status_timeout;
+        let waiting = self.mode != Mode::Normal
+            || self.pending_key.is_some()
+            || self.pending_count.is_some()
+            || self.pending_confirm.is_some();
+        if timeout == 0
+            || waiting
+            || self.status_message.is_empty()
+            || now.duration_since(shown.1) < Duration::from_secs(timeout)
+        {
+            return false;
+        }
+        self.status_message.clear();
+        true
+    }
+
     // Poll the file's modification time; reload unless currently editing.
     fn handle_file_check(&mut self) {
-        let meta = match std::fs::metadata(&self.file_path) {
-            Ok(meta) => meta,
-            Err(err) if err.kind() == io::ErrorKind::NotFound => return,
-            Err(err) => {
-                self.error = Some(err.to_string());
-                return;
-            }
-        };
-        let mod_time = match meta.modified() {
+        self.check_background_tabs();
+        let mod_time = match modified_time(&self.file_path) {
             Ok(time) => time,
+            Err(err) if err.kind() == io::ErrorKind::NotFound => return,
             Err(err) => {
                 self.error = Some(err.to_string());
                 return;
@@ -485,30 +961,122 @@ impl App {
         if !is_modified(mod_time, self.last_modified) {
             return;
         }
+        // Our own saves (or a touch) change the time but not the content.
+        if matches_disk(&self.file_path, &self.lines) {
+            self.last_modified = mod_time;
+            return;
+        }
 
         if self.mode == Mode::Edit {
             self.pending_reload = true;
             return;
         }
+        // Don't clobber changes that failed to save; `r`/`R` decide.
+        if self.dirty {
+            return;
+        }
 
-        match load_lines(&self.file_path) {
-            Ok((lines, mod_time)) => {
+        self.reload("Reloaded from disk", false);
+    }
+
+    // Replace the buffer with the file on disk, dropping unsaved changes.
+    // `reset_history` also clears undo/redo for a clean start.
+    pub(crate) fn reload(&mut self, msg: &str, reset_history: bool) {
+        match load_path(&self.file_path) {
+            Ok((mut lines, mod_time)) => {
+                carry_over_folds(&self.lines, &mut lines);
+                if let Some(unit) = detect_indent_unit(&lines) {
+                    self.indent_unit = unit;
+                }
+                self.line_ending = line_ending_of(&self.file_path);
                 self.lines = lines;
-                self.cursor = clamp_cursor(self.cursor, self.lines.len());
+                self.clamp_cursor_to_visible();
                 self.normalize_selection();
                 self.last_modified = mod_time;
-                self.status_message = "Reloaded from disk".to_string();
-                self.error = None;
                 self.edit_template = default_task_template(&self.lines);
+                self.dirty = false;
+                if reset_history {
+                    self.undo_stack.clear();
+                    self.redo_stack.clear();
+                }
+                self.set_status(msg.to_string());
+                self.error = None;
+                self.report_unrecognized_lines();
             }
             Err(err) => self.error = Some(err.to_string()),
         }
     }
 
+    // Flag task-like lines the parser kept as plain text, so a typo in a
+    // checkbox doesn't quietly turn a task into a note.
+    fn report_unrecognized_lines(&mut self) {
+        let lines = unrecognized_lines(&self.lines);
+        let Some(first) = lines.first() else {
+            return;
+        };
+        for line in &lines {
+            debug!("unrecognized task line: {}", line);
+        }
+        let noun = if lines.len() == 1 { "line" } else { "lines" };
+        let notice = format!(
+            "{} {} not recognized (e.g. {:?})",
+            lines.len(),
+            noun,
+            first.trim()
+        );
+        self.set_status(if self.status_message.is_empty() {
+            notice
+        } else {
+            format!("{} · {}", self.status_message, notice)
+        });
+    }
+
     pub fn count_tasks(&self) -> usize {
         self.lines.iter().filter(|l| l.is_task()).count()
     }
 
+    // Open tasks whose `@due(...)` date is before `today`.
+    pub fn overdue_count(&self, today: Date) -> usize {
+        self.lines
+            .iter()
+            .filter(|line| match line {
+                LineItem::Task(task) => {
+                    !task.completed && due(&task.text).is_some_and(|d| d < today)
+                }
+                _ => false,
+            })
+            .count()
+    }
+
+    // Completion of the direct children nested under the task at `index`, if any.
+    pub fn child_progress(&self, index: usize) -> Option<(usize, usize)> {
+        let LineItem::Task(parent) = self.lines.get(index)? else {
+            return None;
+        };
+        let parent_level = get_indent_level(&parent.indent, &self.indent_unit);
+
+        let mut children = Vec::new();
+        for line in &self.lines[index + 1..] {
+            let task = match line {
+                LineItem::Task(task) => task,
+                // A commented-out subtask doesn't end its parent's block.
+                LineItem::Comment { .. } => continue,
+                _ => break,
+            };
+            let level = get_indent_level(&task.indent, &self.indent_unit);
+            if level <= parent_level {
+                break;
+            }
+            children.push((level, task.completed));
+        }
+
+        let direct_level = children.iter().map(|(level, _)| *level).min()?;
+        let direct = children.iter().filter(|(level, _)| *level == direct_level);
+        let total = direct.clone().count();
+        let done = direct.filter(|(_, completed)| *completed).count();
+        Some((done, total))
+    }
+
     pub fn selection_range(&self) -> Option<(usize, usize)> {
         if !self.selection_active || self.lines.is_empty() {
             return None;
@@ -523,19 +1091,80 @@ impl App {
     }
 
     pub fn is_selected(&self, index: usize) -> bool {
+        if self.marked.contains(&index) {
+            return true;
+        }
         if let Some((start, end)) = self.selection_range() {
             return index >= start && index <= end;
         }
         false
     }
 
-    pub fn clear_selection(&mut self) {
-        if self.selection_active {
-            self.selection_active = false;
+    pub fn has_selection(&self) -> bool {
+        self.selection_active || !self.marked.is_empty()
+    }
+
+    // Every selected line in order: the `V` range plus marked lines. The
+    // range only takes lines on screen, so filtered-out, folded and
+    // collapsed lines between its ends are left alone.
+    pub fn selected_indices(&self) -> Vec<usize> {
+        let mut indices = self.marked.clone();
+        if let Some((start, end)) = self.selection_range() {
+            indices.extend(
+                self.visible_indices()
+                    .into_iter()
+                    .filter(|i| (start..=end).contains(i)),
+            );
         }
+        indices.into_iter().collect()
+    }
+
+    // Add or remove the current line from the marked set.
+    fn toggle_mark(&mut self) {
+        if self.lines.is_empty() {
+            return;
+        }
+        if self.marked.remove(&self.cursor) {
+            self.set_status("Unmarked");
+        } else {
+            self.marked.insert(self.cursor);
+            self.set_status("Marked");
+        }
+    }
+
+    // Changes that quitting would lose: a save that failed in this file, the
+    // inbox or another tab, or an external change still waiting to reload.
+    fn has_unsaved_changes(&self) -> bool {
+        self.dirty
+            || self.pending_reload
+            || self.stashed_buffer.iter().any(|buffer| buffer.dirty)
+            || self.tabs.iter().flatten().any(|buffer| buffer.dirty)
+    }
+
+    // Drop every half-finished interaction (selection, marks, prefix keys,
+    // counts, confirmations) along with the search and the completion and
+    // tag filters, and go back to plain normal mode.
+    fn reset_interaction_state(&mut self) {
+        self.clear_selection();
+        self.pending_key = None;
+        self.pending_count = None;
+        self.pending_confirm = None;
+        self.search_input.reset();
+        self.filter = Filter::All;
+        self.tag_filter = None;
+        self.mode = Mode::Normal;
+        self.status_message.clear();
+        self.clamp_cursor_to_visible();
+    }
+
+    pub fn clear_selection(&mut self) {
+        self.selection_active = false;
+        self.marked.clear();
     }
 
     pub fn normalize_selection(&mut self) {
+        let len = self.lines.len();
+        self.marked.retain(|&i| i < len);
         if !self.selection_active {
             return;
         }
@@ -556,22 +1185,74 @@ impl App {
         !self.search_input.value().is_empty()
     }
 
+    // Case-insensitive match against the raw (unrendered) text.
+    pub(crate) fn matches_search(&self, text: &str) -> bool {
+        !match_ranges(text, self.search_query()).is_empty()
+    }
+
+    // Tasks and section titles that match the search, in file order.
+    fn search_matches(&self) -> Vec<usize> {
+        self.visible_indices()
+            .into_iter()
+            .filter(|&i| match &self.lines[i] {
+                LineItem::Task(task) => self.matches_search(&task.text),
+                LineItem::Section { title, .. } => self.matches_search(title),
+                _ => false,
+            })
+            .collect()
+    }
+
+    // Move to the next (or previous) search match, wrapping around the ends.
+    fn jump_to_match(&mut self, forward: bool) {
+        if !self.search_active() {
+            self.set_status("No search");
+            return;
+        }
+        let matches = self.search_matches();
+        if matches.is_empty() {
+            self.set_status("No matches");
+            return;
+        }
+        let pos = if forward {
+            matches.iter().position(|&i| i > self.cursor).unwrap_or(0)
+        } else {
+            matches
+                .iter()
+                .rposition(|&i| i < self.cursor)
+                .unwrap_or(matches.len() - 1)
+        };
+        self.clear_selection();
+        self.cursor = matches[pos];
+        self.set_status(format!("Match {} of {}", pos + 1, matches.len()));
+    }
+
     pub(crate) fn visible_indices(&self) -> Vec<usize> {
-        if self.mode == Mode::Edit || !self.search_active() {
-            return (0..self.lines.len()).collect();
+        if self.mode == Mode::Edit {
+            return (0..self.lines.len())
+                .filter(|&i| self.show_comments || !self.lines[i].is_comment())
+                .collect();
+        }
+        if !self.search_active() {
+            return self.apply_filter(self.unfolded_indices());
         }
-        let query = self.search_query();
         let mut indices = Vec::new();
         let mut current_section: Option<usize> = None;
         let mut section_included = false;
         for (idx, line) in self.lines.iter().enumerate() {
             match line {
-                LineItem::Section { .. } => {
+                LineItem::Section { title, .. } => {
+                    current_section = Some(idx);
+                    section_included = self.matches_search(title);
+                    if section_included {
+                        indices.push(idx);
+                    }
+                }
+                LineItem::File { .. } => {
                     current_section = Some(idx);
                     section_included = false;
                 }
                 LineItem::Task(task) => {
-                    if task.text.contains(query) {
+                    if self.filter_allows(task) && self.matches_search(&task.text) {
                         if let Some(section_idx) = current_section {
                             if !section_included {
                                 indices.push(section_idx);
@@ -581,17 +1262,42 @@ impl App {
                         indices.push(idx);
                     }
                 }
+                LineItem::Rule | LineItem::Comment { .. } | LineItem::Raw { .. } => {}
             }
         }
         indices
     }
 
-    pub(crate) fn ensure_cursor_visible_for_search(&mut self) {
-        if self.mode == Mode::Edit || !self.search_active() {
+    // Move the cursor onto a visible line after anything that changes what is
+    // shown (search, folds, reloads, deletes): the closest visible line above,
+    // else the first below. While searching, matching tasks are preferred over
+    // their section headers, and blank lines are never landed on. With a
+    // filter on, the line below comes first so a task that was just filtered
+    // out is replaced by the one after it.
+    pub(crate) fn clamp_cursor_to_visible(&mut self) {
+        self.cursor = clamp_cursor(self.cursor, self.lines.len());
+        if self.mode == Mode::Edit {
             return;
         }
         let indices = self.visible_indices();
-        self.ensure_cursor_visible_in(&indices);
+        if indices.contains(&self.cursor) && !self.lines[self.cursor].is_blank() {
+            return;
+        }
+        let mut candidates = indices.clone();
+        candidates.retain(|&i| !self.lines[i].is_blank());
+        if self.search_active() && indices.iter().any(|&i| self.lines[i].is_task()) {
+            candidates.retain(|&i| self.lines[i].is_task());
+        }
+        let above = candidates.iter().rev().find(|&&i| i < self.cursor);
+        let below = candidates.iter().find(|&&i| i > self.cursor);
+        let nearest = if !self.filtering() {
+            above.or(below)
+        } else {
+            below.or(above)
+        };
+        if let Some(&idx) = nearest {
+            self.cursor = idx;
+        }
     }
 
     pub(crate) fn ensure_cursor_visible_in(&mut self, indices: &[usize]) {
@@ -610,7 +1316,7 @@ impl App {
     }
 
     fn move_cursor_visible(&mut self, delta: isize) {
-        let indices = self.visible_indices();
+        let indices = self.navigable_indices();
         if indices.is_empty() {
             return;
         }
@@ -626,20 +1332,72 @@ impl App {
         self.cursor = indices[new_pos];
     }
 
+    // Lines j/k and g/G move between: the visible list without its blank
+    // lines, or the current pane in split view.
+    fn navigable_indices(&self) -> Vec<usize> {
+        if self.split_view {
+            self.pane_indices(self.cursor_in_done_pane())
+        } else {
+            let mut indices = self.visible_indices();
+            indices.retain(|&i| !self.lines[i].is_blank());
+            indices
+        }
+    }
+
     fn move_cursor_to_visible_first(&mut self) {
-        let indices = self.visible_indices();
+        let indices = self.navigable_indices();
         if let Some(&first) = indices.first() {
             self.cursor = first;
         }
     }
 
+    pub(crate) fn move_cursor_to_line(&mut self, line: usize) {
+        self.cursor = clamp_cursor(line.saturating_sub(1), self.lines.len());
+        self.clamp_cursor_to_visible();
+    }
+
     fn move_cursor_to_visible_last(&mut self) {
-        let indices = self.visible_indices();
+        let indices = self.navigable_indices();
         if let Some(&last) = indices.last() {
             self.cursor = last;
         }
     }
 
+    // Jump to the next (or previous) visible task that isn't completed yet.
+    fn move_cursor_to_incomplete(&mut self, forward: bool) {
+        let candidates: Vec<usize> = self
+            .visible_indices()
+            .into_iter()
+            .filter(|&i| matches!(&self.lines[i], LineItem::Task(task) if !task.completed))
+            .collect();
+
+        let next = if forward {
+            candidates.iter().find(|&&i| i > self.cursor)
+        } else {
+            candidates.iter().rev().find(|&&i| i < self.cursor)
+        };
+        if let Some(&idx) = next {
+            self.cursor = idx;
+            return;
+        }
+
+        let wrapped = if forward {
+            candidates.first()
+        } else {
+            candidates.last()
+        };
+        match wrapped {
+            Some(&idx) if self.config.navigation.wrap && idx != self.cursor => {
+                self.cursor = idx;
+                self.set_status("Wrapped");
+            }
+            _ => {
+                let direction = if forward { "below" } else { "above" };
+                self.set_status(format!("No incomplete tasks {}", direction));
+            }
+        }
+    }
+
     pub(crate) fn save_undo_state(&mut self) {
         let state = UndoState {
             lines: self.lines.clone(),
@@ -654,7 +1412,7 @@ impl App {
 
     fn undo(&mut self) {
         if self.undo_stack.is_empty() {
-            self.status_message = "Nothing to undo".to_string();
+            self.set_status("Nothing to undo");
             return;
         }
         let redo_state = UndoState {
@@ -665,14 +1423,15 @@ impl App {
 
         if let Some(state) = self.undo_stack.pop() {
             self.lines = state.lines;
-            self.cursor = clamp_cursor(state.cursor, self.lines.len());
-            self.status_message = "Undo".to_string();
+            self.cursor = state.cursor;
+            self.clamp_cursor_to_visible();
+            self.set_status("Undo");
         }
     }
 
     fn redo(&mut self) {
         if self.redo_stack.is_empty() {
-            self.status_message = "Nothing to redo".to_string();
+            self.set_status("Nothing to redo");
             return;
         }
         let undo_state = UndoState {
@@ -683,20 +1442,24 @@ impl App {
 
         if let Some(state) = self.redo_stack.pop() {
             self.lines = state.lines;
-            self.cursor = clamp_cursor(state.cursor, self.lines.len());
-            self.status_message = "Redo".to_string();
+            self.cursor = state.cursor;
+            self.clamp_cursor_to_visible();
+            self.set_status("Redo");
         }
     }
 }
 
-fn default_task_template(lines: &[LineItem]) -> Task {
+pub(crate) fn default_task_template(lines: &[LineItem]) -> Task {
     for line in lines {
         if let LineItem::Task(task) = line {
             return Task {
                 indent: task.indent.clone(),
                 bullet: task.bullet.clone(),
                 completed: false,
+                mark: 'x',
                 text: String::new(),
+                folded: false,
+                file_indent: None,
             };
         }
     }
@@ -704,12 +1467,15 @@ fn default_task_template(lines: &[LineItem]) -> Task {
         indent: String::new(),
         bullet: "-".to_string(),
         completed: false,
+        mark: 'x',
         text: String::new(),
+        folded: false,
+        file_indent: None,
     }
 }
 
 fn is_modified(current: SystemTime, last: SystemTime) -> bool {
-    current.duration_since(last).is_ok()
+    current > last
 }
 
 struct TerminalGuard;
@@ -719,6 +1485,7 @@ impl TerminalGuard {
         enable_raw_mode()?;
         let mut stdout = io::stdout();
         stdout.execute(EnterAlternateScreen)?;
+        stdout.execute(EnableMouseCapture)?;
         stdout.execute(Hide)?;
         Ok(Self)
     }
@@ -728,7 +1495,11 @@ impl Drop for TerminalGuard {
     fn drop(&mut self) {
         let _ = disable_raw_mode();
         let mut stdout = io::stdout();
+        let _ = stdout.execute(DisableMouseCapture);
         let _ = stdout.execute(LeaveAlternateScreen);
         let _ = stdout.execute(Show);
     }
 }
+
+#[cfg(test)]
+pub(crate) mod tests;
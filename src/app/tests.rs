@@ -0,0 +1,307 @@
+use std::fs;
+use std::time::Duration;
+
+use tempfile::TempDir;
+
+use crate::ansi::{strip_ansi, visible_width};
+use crate::render::HIGHLIGHT_ON;
+
+use super::*;
+
+// An app on a scratch copy of `content`; keep the dir alive for the test.
+pub(crate) fn app_with(content: &str) -> (TempDir, App) {
+    let dir = TempDir::new().expect("temp dir");
+    let path = dir.path().join("todo.md");
+    fs::write(&path, content).expect("write todo.md");
+    let app = App::new(path, Config::default()).expect("load todo.md");
+    (dir, app)
+}
+
+fn saved(app: &App) -> String {
+    fs::read_to_string(&app.file_path).expect("read todo.md")
+}
+
+#[test]
+fn visual_delete_skips_filtered_tasks() {
+    let (_dir, mut app) = app_with("## A\n- [ ] one\n- [x] two\n- [ ] three\n");
+    app.handle_key(Key::Char('f'));
+    app.cursor = 1;
+    app.handle_key(Key::Char('V'));
+    app.handle_key(Key::Char('j'));
+    assert_eq!(app.selected_indices(), vec![1, 3]);
+    app.handle_key(Key::Char('d'));
+    assert_eq!(saved(&app), "## A\n- [x] two\n");
+}
+
+#[test]
+fn commented_out_subtask_stays_in_parent_block() {
+    let (_dir, mut app) =
+        app_with("## A\n- [ ] parent\n  - [x] one\n  - [ ] two\n  - [x] three\n- [ ] next\n");
+    app.cursor = 3;
+    app.handle_key(Key::Char('#'));
+    assert!(app.lines[3].is_comment());
+    assert_eq!(app.task_block_end(1), 5);
+    assert_eq!(app.child_progress(1), Some((2, 2)));
+}
+
+#[test]
+fn trailing_comment_is_not_part_of_block() {
+    let (_dir, app) = app_with("## A\n- [ ] parent\n  - [ ] child\n<!-- note -->\n- [ ] next\n");
+    assert_eq!(app.task_block_end(1), 3);
+}
+
+#[test]
+fn done_stamp_only_touches_toggled_task() {
+    let (_dir, mut app) = app_with("## A\n- [ ] one\n- [x] two\n- [ ] three @done(2020-01-01)\n");
+    app.config.done.stamp = true;
+    app.cursor = 1;
+    app.toggle_tasks();
+    let stamp = Date::today().format(&app.config.done.format);
+    assert_eq!(
+        saved(&app),
+        format!(
+            "## A\n- [x] one @done({})\n- [x] two\n- [ ] three @done(2020-01-01)\n",
+            stamp
+        )
+    );
+    app.toggle_tasks();
+    assert_eq!(
+        saved(&app),
+        "## A\n- [ ] one\n- [x] two\n- [ ] three @done(2020-01-01)\n"
+    );
+}
+
+// Benchmark for the markdown cache: `cargo test --release -- --ignored
+// --nocapture render_benchmark`.
+#[test]
+#[ignore]
+fn render_benchmark() {
+    use std::time::Instant;
+
+    let mut content = String::from("## Tasks\n");
+    for i in 0..2000 {
+        content.push_str(&format!(
+            "- [ ] task {} with **bold**, `code` and [a link](https://example.com/{})\n",
+            i, i
+        ));
+    }
+    let (_dir, mut app) = app_with(&content);
+    app.window_width = 120;
+    app.window_height = 40;
+    app.ensure_renderer_width(app.window_width);
+    // Render every line, not just one screen.
+    let render_all = |app: &App| {
+        app.lines
+            .iter()
+            .filter_map(|line| match line {
+                LineItem::Task(task) => Some(app.render_task_text(&task.text, app.renderer_width)),
+                _ => None,
+            })
+            .count()
+    };
+
+    app.markdown_cache.get_mut().clear();
+    let start = Instant::now();
+    assert_eq!(render_all(&app), 2000);
+    let cold = start.elapsed();
+    let start = Instant::now();
+    assert_eq!(render_all(&app), 2000);
+    let warm = start.elapsed();
+    assert_eq!(app.markdown_cache.borrow().len(), 2000);
+    let start = Instant::now();
+    app.render();
+    let view = start.elapsed();
+
+    println!(
+        "2000 tasks: cold {:?}, warm {:?}, render() {:?}",
+        cold, warm, view
+    );
+}
+
+#[test]
+fn selection_highlight_fits_window_after_resize() {
+    let long = "word ".repeat(30);
+    let (_dir, mut app) = app_with(&format!("## A\n- [ ] {}\n- [ ] {}\n", long, long));
+    app.window_width = 120;
+    app.window_height = 40;
+    app.ensure_renderer_width(120);
+    app.cursor = 1;
+    app.handle_key(Key::Char('V'));
+    app.handle_key(Key::Char('j'));
+    app.render();
+
+    app.window_width = 40;
+    app.ensure_renderer_width(40);
+    let view = app.render();
+    let highlighted: Vec<&str> = view
+        .lines()
+        .filter(|row| row.contains(HIGHLIGHT_ON))
+        .collect();
+    assert!(!highlighted.is_empty());
+    for row in highlighted {
+        assert!(visible_width(row) <= 40, "row too wide: {:?}", row);
+    }
+}
+
+#[test]
+fn selection_highlight_kept_before_window_size_is_known() {
+    let (_dir, mut app) = app_with("## A\n- [ ] one\n");
+    app.window_width = 0;
+    app.cursor = 1;
+    app.handle_key(Key::Char('V'));
+    let view = app.render();
+    let row = view
+        .lines()
+        .find(|row| row.contains(HIGHLIGHT_ON))
+        .expect("highlighted row");
+    assert!(strip_ansi(row).contains("one"));
+}
+
+#[test]
+fn filter_moves_cursor_off_hidden_task() {
+    let (_dir, mut app) = app_with("## A\n- [ ] one\n- [x] two\n- [ ] three\n- [x] four\n");
+    app.cursor = 2;
+    app.handle_key(Key::Char('f'));
+    assert_eq!(app.cursor, 3);
+
+    // Done, then off again; the last task has nothing visible below it.
+    app.handle_key(Key::Char('f'));
+    app.handle_key(Key::Char('f'));
+    app.cursor = 4;
+    app.handle_key(Key::Char('f'));
+    assert_eq!(app.cursor, 3);
+}
+
+#[test]
+fn help_shows_remapped_keys() {
+    let (_dir, mut app) = app_with("## A\n- [ ] one\n");
+    // `[keys] down = "x"` and `delete = "j"`.
+    app.config.keys.remap = vec![('x', 'j'), ('j', 'd')];
+    let help: Vec<String> = app.help_lines().iter().map(|l| strip_ansi(l)).collect();
+    let row = |desc: &str| {
+        help.iter()
+            .find(|line| line.contains(desc))
+            .unwrap_or_else(|| panic!("no help row for {:?}", desc))
+            .split_whitespace()
+            .next()
+            .unwrap()
+            .to_string()
+    };
+    assert_eq!(row("move down/up (5x: five lines)"), "x/k,");
+    assert_eq!(row("delete lines"), "jj,");
+    assert_eq!(row("toggle completion"), "Space/Enter");
+}
+
+#[test]
+fn sort_section_can_be_previewed() {
+    let content = "## A\n- [ ] low !3\n- [ ] high !1\n";
+    let (_dir, mut app) = app_with(content);
+    app.config.bulk.preview = true;
+    app.cursor = 1;
+    app.handle_key(Key::Char('s'));
+    assert!(app.preview.is_some());
+    assert_eq!(saved(&app), content);
+
+    app.handle_key(Key::Char('y'));
+    assert_eq!(saved(&app), "## A\n- [ ] high !1\n- [ ] low !3\n");
+    assert_eq!(app.cursor, 2);
+}
+
+#[test]
+fn repeated_status_message_restarts_timeout() {
+    let (_dir, mut app) = app_with("## A\n- [ ] one\n");
+    app.config.display.status_timeout = 3;
+    let start = Instant::now();
+    app.set_status("Saved");
+    let mut shown = (0, start);
+    assert!(!app.expire_status(&mut shown, start));
+
+    // The same text again, two seconds in, counts as a new message.
+    app.set_status("Saved");
+    assert!(!app.expire_status(&mut shown, start + Duration::from_secs(2)));
+    assert!(!app.expire_status(&mut shown, start + Duration::from_secs(4)));
+    assert!(app.expire_status(&mut shown, start + Duration::from_secs(5)));
+    assert!(app.status_message.is_empty());
+}
+
+#[test]
+fn counted_delete_skips_hidden_lines() {
+    let (_dir, mut app) =
+        app_with("## A\n- [ ] one\n  - [ ] folded child\n- [x] done\n\n- [ ] two\n- [ ] three\n");
+    app.cursor = 1;
+    app.handle_key(Key::Tab);
+    app.handle_key(Key::Char('f'));
+    app.cursor = 1;
+    app.handle_key(Key::Char('2'));
+    app.handle_key(Key::Char('d'));
+    app.handle_key(Key::Char('d'));
+    assert_eq!(
+        saved(&app),
+        "## A\n  - [ ] folded child\n- [x] done\n\n- [ ] three\n"
+    );
+}
+
+#[test]
+fn single_delete_removes_raw_and_comment_lines() {
+    let (_dir, mut app) = app_with("## A\nsome notes\n<!-- hidden -->\n- [ ] one\n");
+    app.show_comments = true;
+    app.cursor = 1;
+    app.handle_key(Key::Char('d'));
+    app.handle_key(Key::Char('d'));
+    app.cursor = 1;
+    app.handle_key(Key::Char('d'));
+    app.handle_key(Key::Char('d'));
+    assert_eq!(saved(&app), "## A\n- [ ] one\n");
+}
+
+#[test]
+fn counted_yank_skips_hidden_lines() {
+    let (_dir, mut app) = app_with("## A\n- [ ] one\n- [x] done\n- [ ] two\n");
+    app.handle_key(Key::Char('f'));
+    app.cursor = 1;
+    app.handle_key(Key::Char('2'));
+    app.handle_key(Key::Char('y'));
+    app.handle_key(Key::Char('y'));
+    let yanked: Vec<String> = app.register.iter().map(LineItem::line).collect();
+    assert_eq!(yanked, vec!["- [ ] one", "- [ ] two"]);
+}
+
+#[test]
+fn off_grid_indents_are_saved_as_written() {
+    let content = "## A\n- [ ] a\n    - [ ] b\n      - [ ] c\n";
+    let (_dir, mut app) = app_with(content);
+    app.cursor = 1;
+    app.toggle_tasks();
+    assert_eq!(saved(&app), "## A\n- [x] a\n    - [ ] b\n      - [ ] c\n");
+
+    // Reindenting the task itself puts it on the grid.
+    app.cursor = 3;
+    app.handle_key(Key::Char('<'));
+    app.handle_key(Key::Char('<'));
+    assert_eq!(saved(&app), "## A\n- [x] a\n    - [ ] b\n    - [ ] c\n");
+}
+
+#[test]
+fn ctrl_g_clears_search_and_filters() {
+    let (_dir, mut app) = app_with("## A\n- [ ] one #x\n- [x] done\n");
+    app.handle_key(Key::Char('f'));
+    app.tag_filter = Some("x".into());
+    app.handle_key(Key::Ctrl('g'));
+    assert_eq!(app.filter, Filter::All);
+    assert!(app.tag_filter.is_none());
+
+    app.handle_key(Key::Char('/'));
+    app.handle_key(Key::Char('o'));
+    assert_eq!(app.mode, Mode::Search);
+    app.handle_key(Key::Ctrl('g'));
+    assert_eq!(app.mode, Mode::Normal);
+    assert!(app.search_input.value().is_empty());
+}
+
+#[test]
+fn unchanged_modification_time_is_not_a_change() {
+    let now = SystemTime::now();
+    assert!(!is_modified(now, now));
+    assert!(is_modified(now + Duration::from_secs(1), now));
+    assert!(!is_modified(now, now + Duration::from_secs(1)));
+}
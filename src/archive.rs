@@ -0,0 +1,69 @@
+use crate::date::Date;
+use crate::edit::get_indent_level;
+use crate::metadata::done_date;
+use crate::model::{App, LineItem};
+
+impl App {
+    // The `@done(...)` stamp for tasks completed now, when `[done] stamp` or
+    // archiving is on; archiving needs it to know a task's age.
+    pub(crate) fn done_stamp(&self) -> Option<String> {
+        if !self.config.done.stamp && self.config.archive.after_days == 0 {
+            return None;
+        }
+        Some(Date::today().format(&self.config.done.format))
+    }
+
+    // Move top-level tasks completed more than `[archive] after_days` ago
+    // (with their subtasks) to the end of the archive section, adding the
+    // section if it's missing.
+    pub(crate) fn auto_archive(&mut self, today: Date) {
+        let after_days = self.config.archive.after_days as i64;
+        if after_days == 0 {
+            return;
+        }
+        let title = self.config.archive.section.clone();
+        let archive_header = |lines: &[LineItem]| {
+            lines.iter().position(
+                |line| matches!(line, LineItem::Section { title: t, .. } if t.eq_ignore_ascii_case(&title)),
+            )
+        };
+        // The next stale task that isn't already under the archive header.
+        let next_stale = |app: &App| {
+            let header = archive_header(&app.lines);
+            (0..app.lines.len()).find(|&i| match &app.lines[i] {
+                LineItem::Task(task) => {
+                    task.completed
+                        && get_indent_level(&task.indent, &app.indent_unit) == 0
+                        && done_date(&task.text)
+                            .is_some_and(|date| today.days_since(date) > after_days)
+                        && (header.is_none() || app.enclosing_header(i) != header)
+                }
+                _ => false,
+            })
+        };
+        if next_stale(self).is_none() {
+            return;
+        }
+
+        if archive_header(&self.lines).is_none() {
+            self.lines.push(LineItem::Section {
+                title: title.clone(),
+                collapsed: false,
+            });
+        }
+        let cursor = self.cursor;
+        let mut count = 0;
+        while let Some(idx) = next_stale(self) {
+            let Some(header) = archive_header(&self.lines) else {
+                break;
+            };
+            self.cursor = idx;
+            self.relocate_task_to_section(header);
+            count += 1;
+        }
+        self.cursor = cursor;
+        self.clamp_cursor_to_visible();
+        let noun = if count == 1 { "task" } else { "tasks" };
+        self.save_and_set_status(&format!("Auto-archived {} {}", count, noun));
+    }
+}
@@ -0,0 +1,102 @@
+use crate::keys::Key;
+use crate::metadata::strip_done;
+use crate::model::{App, Confirm, LineItem};
+
+impl App {
+    // Ask for a y/n answer before running a command that is hard to take back.
+    pub fn request_confirm(&mut self, confirm: Confirm) {
+        let total = self.count_tasks();
+        let bulk = !matches!(confirm, Confirm::Reload | Confirm::Quit);
+        if total == 0 && bulk {
+            self.set_status("No tasks");
+            return;
+        }
+        self.clear_selection();
+        // The preview overlay asks for confirmation itself.
+        if self.config.bulk.preview && bulk {
+            self.run_confirmed(confirm);
+            return;
+        }
+        self.pending_confirm = Some(confirm);
+        self.set_status(match confirm {
+            Confirm::InvertAll => format!("Invert all {} tasks? (y/n)", total),
+            Confirm::ResetAll => format!("Reset all {} tasks to incomplete? (y/n)", total),
+            Confirm::Reload => "Discard unsaved changes and reload? (y/n)".to_string(),
+            Confirm::Quit => "Discard changes? (y/n)".to_string(),
+        });
+    }
+
+    pub(crate) fn handle_confirm_key(&mut self, confirm: Confirm, key: Key) {
+        if !matches!(key, Key::Char('y') | Key::Char('Y')) {
+            self.set_status("Canceled");
+            return;
+        }
+        self.run_confirmed(confirm);
+    }
+
+    fn run_confirmed(&mut self, confirm: Confirm) {
+        match confirm {
+            Confirm::InvertAll => self.invert_all(),
+            Confirm::ResetAll => self.reset_all(),
+            Confirm::Reload => self.reload("Reloaded", false),
+            Confirm::Quit => self.should_quit = true,
+        }
+    }
+
+    fn invert_all(&mut self) {
+        let mut lines = self.lines.clone();
+        let mut count = 0;
+        let mark = self.config.format.done_mark;
+        let stamp = self.done_stamp();
+        for line in &mut lines {
+            if let LineItem::Task(task) = line {
+                task.set_completed(!task.completed, mark, stamp.as_deref());
+                count += 1;
+            }
+        }
+        self.apply_bulk(lines, &format!("Inverted {} tasks", count));
+    }
+
+    // Turn a finished checklist back into a fresh one.
+    fn reset_all(&mut self) {
+        let mut lines = self.lines.clone();
+        let mut count = 0;
+        for line in &mut lines {
+            if let LineItem::Task(task) = line {
+                let text = strip_done(&task.text);
+                if task.completed || text != task.text {
+                    task.completed = false;
+                    task.text = text;
+                    count += 1;
+                }
+            }
+        }
+        self.apply_bulk(lines, &format!("Reset {} tasks", count));
+    }
+
+    // Complete every subtask nested under the current task, or reopen them
+    // all when they are already done. The parent itself is left alone.
+    pub fn toggle_subtasks(&mut self) {
+        let end = self.task_block_end(self.cursor);
+        if end <= self.cursor + 1 {
+            self.set_status("No subtasks to toggle");
+            return;
+        }
+        let children = self.cursor + 1..end;
+        let completed = self.lines[children.clone()]
+            .iter()
+            .any(|line| matches!(line, LineItem::Task(task) if !task.completed));
+
+        self.save_undo_state();
+        self.clear_selection();
+        let mark = self.config.format.done_mark;
+        let stamp = self.done_stamp();
+        for line in &mut self.lines[children.clone()] {
+            if let LineItem::Task(task) = line {
+                task.set_completed(completed, mark, stamp.as_deref());
+            }
+        }
+        let state = if completed { "Completed" } else { "Reopened" };
+        self.save_and_set_status(&format!("{} {} subtasks", state, children.len()));
+    }
+}
@@ -0,0 +1,238 @@
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use crate::export::{export_html, export_text};
+use crate::io::{line_ending_of, load_path, pending_data_loss, save_path, write_backup};
+use crate::model::{task_progress, LineItem};
+
+pub const DONE_USAGE: &str = "usage: lazytodo done [--section name] <text> [path|directory]";
+pub const SUMMARY_USAGE: &str = "usage: lazytodo --summary [--format fmt] [path|directory]";
+pub const SUMMARY_FORMAT: &str = "{file}: {done}/{total} done ({percent}%)";
+pub const EXPORT_USAGE: &str =
+    "usage: lazytodo --export html|text [--output file] [path|directory]";
+
+// Arguments for `lazytodo done`, which toggles one task without the TUI.
+#[derive(Debug)]
+pub struct DoneArgs {
+    pub text: String,
+    pub section: Option<String>,
+    pub path: Option<PathBuf>,
+}
+
+pub fn parse_done_args(args: &[String]) -> Result<DoneArgs, String> {
+    let mut section = None;
+    let mut positional = Vec::new();
+    let mut iter = args.iter();
+    while let Some(arg) = iter.next() {
+        match arg.as_str() {
+            "--section" | "-s" => match iter.next() {
+                Some(name) => section = Some(name.clone()),
+                None => return Err(DONE_USAGE.to_string()),
+            },
+            _ => positional.push(arg.clone()),
+        }
+    }
+
+    let mut positional = positional.into_iter();
+    let (Some(text), path, None) = (positional.next(), positional.next(), positional.next()) else {
+        return Err(DONE_USAGE.to_string());
+    };
+    Ok(DoneArgs {
+        text,
+        section,
+        path: path.map(PathBuf::from),
+    })
+}
+
+// Arguments for `lazytodo --summary`, which prints one progress line.
+#[derive(Debug)]
+pub struct SummaryArgs {
+    pub format: String,
+    pub path: Option<PathBuf>,
+}
+
+pub fn parse_summary_args(args: &[String]) -> Result<SummaryArgs, String> {
+    let mut format = SUMMARY_FORMAT.to_string();
+    let mut path = None;
+    let mut iter = args.iter();
+    while let Some(arg) = iter.next() {
+        match arg.as_str() {
+            "--summary" => {}
+            "--format" | "-f" => match iter.next() {
+                Some(fmt) => format = fmt.clone(),
+                None => return Err(SUMMARY_USAGE.to_string()),
+            },
+            _ if path.is_none() => path = Some(PathBuf::from(arg)),
+            _ => return Err(SUMMARY_USAGE.to_string()),
+        }
+    }
+    Ok(SummaryArgs { format, path })
+}
+
+// Output formats for `lazytodo --export`.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ExportFormat {
+    Html,
+    Text,
+}
+
+impl ExportFormat {
+    fn extension(self) -> &'static str {
+        match self {
+            ExportFormat::Html => "html",
+            ExportFormat::Text => "txt",
+        }
+    }
+}
+
+// Arguments for `lazytodo --export`, which writes the list in another format.
+#[derive(Debug)]
+pub struct ExportArgs {
+    pub format: ExportFormat,
+    pub output: Option<PathBuf>,
+    pub path: Option<PathBuf>,
+}
+
+pub fn parse_export_args(args: &[String]) -> Result<ExportArgs, String> {
+    let mut format = None;
+    let mut output = None;
+    let mut path = None;
+    let mut iter = args.iter();
+    while let Some(arg) = iter.next() {
+        match arg.as_str() {
+            "--export" => {
+                format = match iter.next().map(String::as_str) {
+                    Some("html") => Some(ExportFormat::Html),
+                    Some("text") => Some(ExportFormat::Text),
+                    _ => return Err(EXPORT_USAGE.to_string()),
+                }
+            }
+            "--output" | "-o" => match iter.next() {
+                Some(file) => output = Some(PathBuf::from(file)),
+                None => return Err(EXPORT_USAGE.to_string()),
+            },
+            _ if path.is_none() => path = Some(PathBuf::from(arg)),
+            _ => return Err(EXPORT_USAGE.to_string()),
+        }
+    }
+    let format = format.ok_or_else(|| EXPORT_USAGE.to_string())?;
+    Ok(ExportArgs {
+        format,
+        output,
+        path,
+    })
+}
+
+// Write the list at `path` to `output` (default: the same name with an .html
+// or .txt extension), returning a summary line.
+pub fn export(path: &Path, format: ExportFormat, output: Option<&Path>) -> Result<String, String> {
+    let (lines, _) = load_path(path).map_err(|e| e.to_string())?;
+    let output = output
+        .map(Path::to_path_buf)
+        .unwrap_or_else(|| path.with_extension(format.extension()));
+    if output == path || fs::canonicalize(&output).is_ok_and(|out| out == path) {
+        return Err(format!("refusing to overwrite {}", path.display()));
+    }
+    let title = path
+        .file_name()
+        .map(|name| name.to_string_lossy().into_owned())
+        .unwrap_or_else(|| path.display().to_string());
+    let content = match format {
+        ExportFormat::Html => export_html(&lines, &title),
+        ExportFormat::Text => export_text(&lines, &title),
+    };
+    fs::write(&output, content).map_err(|e| format!("{}: {}", output.display(), e))?;
+    Ok(format!("Exported {} to {}", title, output.display()))
+}
+
+// One-line progress for shell prompts and status bars. The format may use
+// {file}, {done}, {open}, {total} and {percent}.
+pub fn summary(path: &Path, format: &str) -> Result<String, String> {
+    let (lines, _) = load_path(path).map_err(|e| e.to_string())?;
+    let (done, total) = task_progress(&lines);
+    let percent = if total == 0 { 0 } else { done * 100 / total };
+    let file = path
+        .file_name()
+        .map(|name| name.to_string_lossy().into_owned())
+        .unwrap_or_else(|| path.display().to_string());
+    Ok(format
+        .replace("{file}", &file)
+        .replace("{done}", &done.to_string())
+        .replace("{open}", &(total - done).to_string())
+        .replace("{total}", &total.to_string())
+        .replace("{percent}", &percent.to_string()))
+}
+
+// Toggle the first task matching `query` and save, returning a summary line.
+// An exact (case-insensitive) match wins over a substring match, and a
+// task that gets completed is written with `mark` in its checkbox.
+pub fn toggle_by_text(
+    path: &Path,
+    query: &str,
+    section: Option<&str>,
+    mark: char,
+) -> Result<String, String> {
+    let (mut lines, _) = load_path(path).map_err(|e| e.to_string())?;
+    let Some(index) = find_task(&lines, query, section) else {
+        return Err(match section {
+            Some(section) => format!("no task matching {:?} in section {:?}", query, section),
+            None => format!("no task matching {:?}", query),
+        });
+    };
+
+    let LineItem::Task(task) = &mut lines[index] else {
+        unreachable!("find_task only returns tasks");
+    };
+    task.set_completed(!task.completed, mark, None);
+    let summary = format!(
+        "{}: {}",
+        if task.completed {
+            "Completed"
+        } else {
+            "Reopened"
+        },
+        task.text
+    );
+
+    for loss in pending_data_loss(path, &lines) {
+        let backup = write_backup(&loss.path).map_err(|e| e.to_string())?;
+        eprintln!(
+            "warning: {} lines rewritten in {}, original kept in {}",
+            loss.lines.len(),
+            loss.path.display(),
+            backup.display()
+        );
+    }
+    save_path(path, &lines, line_ending_of(path)).map_err(|e| e.to_string())?;
+    Ok(summary)
+}
+
+fn find_task(lines: &[LineItem], query: &str, section: Option<&str>) -> Option<usize> {
+    let query = query.to_lowercase();
+    let section = section.map(str::to_lowercase);
+    let mut current_section: Option<String> = None;
+    let mut candidates = Vec::new();
+    for (idx, line) in lines.iter().enumerate() {
+        match line {
+            LineItem::Section { title, .. } => current_section = Some(title.to_lowercase()),
+            LineItem::File { .. } => current_section = None,
+            LineItem::Rule | LineItem::Comment { .. } | LineItem::Raw { .. } => {}
+            LineItem::Task(task) => {
+                let in_section = match (&section, &current_section) {
+                    (None, _) => true,
+                    (Some(wanted), Some(current)) => current.contains(wanted.as_str()),
+                    (Some(_), None) => false,
+                };
+                if in_section {
+                    candidates.push((idx, task.text.to_lowercase()));
+                }
+            }
+        }
+    }
+
+    candidates
+        .iter()
+        .find(|(_, text)| text.trim() == query.trim())
+        .or_else(|| candidates.iter().find(|(_, text)| text.contains(&query)))
+        .map(|(idx, _)| *idx)
+}
@@ -0,0 +1,38 @@
+use std::io::{self, Write};
+use std::process::{Command, Stdio};
+
+// Clipboard helpers tried in order; the first one installed wins.
+const CLIPBOARD_COMMANDS: &[(&str, &[&str])] = &[
+    ("pbcopy", &[]),
+    ("wl-copy", &[]),
+    ("xclip", &["-selection", "clipboard"]),
+    ("xsel", &["--clipboard", "--input"]),
+];
+
+pub fn copy_to_clipboard(text: &str) -> Result<(), String> {
+    for (program, args) in CLIPBOARD_COMMANDS {
+        let mut child = match Command::new(program)
+            .args(*args)
+            .stdin(Stdio::piped())
+            .stdout(Stdio::null())
+            .stderr(Stdio::null())
+            .spawn()
+        {
+            Ok(child) => child,
+            Err(err) if err.kind() == io::ErrorKind::NotFound => continue,
+            Err(err) => return Err(err.to_string()),
+        };
+
+        if let Some(mut stdin) = child.stdin.take() {
+            stdin
+                .write_all(text.as_bytes())
+                .map_err(|e| e.to_string())?;
+        }
+        let status = child.wait().map_err(|e| e.to_string())?;
+        if !status.success() {
+            return Err(format!("{} failed", program));
+        }
+        return Ok(());
+    }
+    Err("No clipboard tool found (install pbcopy, wl-copy, xclip, or xsel)".to_string())
+}
@@ -0,0 +1,66 @@
+use crate::metadata::{color, set_color};
+use crate::model::{App, LineItem};
+
+// Colors offered by the `c` prefix: key, name stored in `@color(...)`, and
+// the ANSI foreground used to draw it.
+pub const PALETTE: [(char, &str, &str); 6] = [
+    ('r', "red", "\x1b[31m"),
+    ('g', "green", "\x1b[32m"),
+    ('y', "yellow", "\x1b[33m"),
+    ('b', "blue", "\x1b[34m"),
+    ('m', "magenta", "\x1b[35m"),
+    ('c', "cyan", "\x1b[36m"),
+];
+
+pub const COLOR_OFF: &str = "\x1b[39m";
+
+// ANSI foreground for a color name; None for names outside the palette.
+pub fn color_code(name: &str) -> Option<&'static str> {
+    PALETTE
+        .iter()
+        .find(|(_, known, _)| known.eq_ignore_ascii_case(name))
+        .map(|(_, _, code)| *code)
+}
+
+impl App {
+    pub(crate) fn color_prompt(&self) -> String {
+        let choices: Vec<String> = PALETTE
+            .iter()
+            .map(|(key, name, _)| format!("{} {}", key, name))
+            .collect();
+        format!("c- {} · x clear", choices.join(" · "))
+    }
+
+    // Set the current task's `@color(...)` from the palette key, or clear it
+    // with `x`.
+    pub fn set_task_color(&mut self, key: char) {
+        let name = match PALETTE.iter().find(|(k, _, _)| *k == key) {
+            Some((_, name, _)) => Some(*name),
+            None if key == 'x' => None,
+            None => {
+                self.set_status(format!("No color on {}", key));
+                return;
+            }
+        };
+        let Some(LineItem::Task(task)) = self.lines.get(self.cursor) else {
+            self.set_status("No task to color");
+            return;
+        };
+        if color(&task.text) == name {
+            return;
+        }
+        let text = set_color(&task.text, name);
+
+        self.save_undo_state();
+        self.clear_selection();
+        if let Some(LineItem::Task(task)) = self.lines.get_mut(self.cursor) {
+            task.text = text;
+        }
+        self.remember_edit(self.cursor);
+        let msg = match name {
+            Some(name) => format!("Colored {}", name),
+            None => "Color cleared".to_string(),
+        };
+        self.save_and_set_status(&msg);
+    }
+}
@@ -0,0 +1,52 @@
+use crate::keys::Key;
+use crate::model::{App, Mode};
+
+impl App {
+    // `:` opens a one-line command prompt in the footer.
+    pub fn open_command_line(&mut self) {
+        self.clear_selection();
+        self.command_input.reset();
+        self.mode = Mode::Command;
+    }
+
+    pub(crate) fn handle_command_key(&mut self, key: Key) {
+        match key {
+            Key::Esc | Key::Ctrl('c') => self.mode = Mode::Normal,
+            Key::Enter => {
+                self.mode = Mode::Normal;
+                let command = self.command_input.value().trim().to_string();
+                self.run_command(&command);
+            }
+            Key::Char(c) => self.command_input.insert_char(c),
+            Key::Backspace => {
+                // Backspace on an empty prompt leaves it, as in vim.
+                if self.command_input.value().is_empty() {
+                    self.mode = Mode::Normal;
+                    return;
+                }
+                self.command_input.backspace();
+            }
+            Key::Delete => self.command_input.delete(),
+            Key::Left => self.command_input.move_left(),
+            Key::Right => self.command_input.move_right(),
+            Key::Home => self.command_input.move_home(),
+            Key::End => self.command_input.move_end(),
+            _ => {}
+        }
+    }
+
+    // `:<n>` goes to line n of the file (1-based, clamped to the last line).
+    // Anything else is rejected and the cursor stays where it was.
+    fn run_command(&mut self, command: &str) {
+        if command.is_empty() {
+            return;
+        }
+        match command.parse::<usize>() {
+            Ok(line) => {
+                self.move_cursor_to_line(line);
+                self.set_status(format!("Line {}", self.cursor + 1));
+            }
+            Err(_) => self.set_status(format!("Not a command: :{}", command)),
+        }
+    }
+}
@@ -0,0 +1,135 @@
+use crate::edit::get_indent_level;
+use crate::io::parse_task;
+use crate::model::{App, LineItem, Task};
+
+impl App {
+    // Show or hide `<!-- ... -->` comment lines.
+    pub fn toggle_comments(&mut self) {
+        self.show_comments = !self.show_comments;
+        self.clear_selection();
+        self.clamp_cursor_to_visible();
+        self.set_status(if self.show_comments {
+            "Showing comments"
+        } else {
+            "Comments hidden"
+        });
+    }
+
+    // Disable the current task and its subtasks by turning each into a
+    // comment line, so they stay in the file but drop out of the list.
+    pub fn comment_out_task(&mut self) {
+        if !matches!(self.lines.get(self.cursor), Some(LineItem::Task(_))) {
+            self.set_status("No task to comment out");
+            return;
+        }
+        let end = self.task_block_end(self.cursor);
+        let count = end - self.cursor;
+
+        self.save_undo_state();
+        self.clear_selection();
+        for line in &mut self.lines[self.cursor..end] {
+            if let LineItem::Task(task) = line {
+                *line = LineItem::Comment {
+                    text: comment_task(task),
+                };
+            }
+        }
+        self.clamp_cursor_to_visible();
+        let msg = if count == 1 {
+            "Commented out task".to_string()
+        } else {
+            format!("Commented out {} tasks", count)
+        };
+        self.save_and_set_status(&msg);
+    }
+
+    // Turn the comment line under the cursor back into a task, along with
+    // the commented-out subtasks nested under it. Comments that aren't a
+    // checkbox line become a new open task with the comment's text.
+    pub fn uncomment_task(&mut self) {
+        let Some(LineItem::Comment { text }) = self.lines.get(self.cursor) else {
+            self.set_status("Not a comment");
+            return;
+        };
+        // Restoring one line of a multi-line comment would orphan the rest.
+        if !is_single_line(text) {
+            self.set_status("Only single-line comments can be restored");
+            return;
+        }
+        let first = uncomment_task(text);
+        if first.text.trim().is_empty() {
+            self.set_status("Empty comment");
+            return;
+        }
+        let level = get_indent_level(&first.indent, &self.indent_unit);
+        let mut tasks = vec![first];
+        for line in &self.lines[self.cursor + 1..] {
+            let LineItem::Comment { text } = line else {
+                break;
+            };
+            match commented_task(text).filter(|_| is_single_line(text)) {
+                Some(task) if get_indent_level(&task.indent, &self.indent_unit) > level => {
+                    tasks.push(task)
+                }
+                _ => break,
+            }
+        }
+
+        self.save_undo_state();
+        self.clear_selection();
+        let count = tasks.len();
+        let start = self.cursor;
+        self.lines
+            .splice(start..start + count, tasks.into_iter().map(LineItem::Task));
+        self.expand_section_containing(start);
+        let msg = if count == 1 {
+            "Restored task".to_string()
+        } else {
+            format!("Restored {} tasks", count)
+        };
+        self.save_and_set_status(&msg);
+    }
+}
+
+// `- [ ] text` becomes `<!-- - [ ] text -->`, keeping the indentation outside
+// the comment so nesting survives a round trip.
+fn comment_task(task: &Task) -> String {
+    let line = task.line();
+    format!("{}<!-- {} -->", task.indent, line.trim_start())
+}
+
+fn is_single_line(text: &str) -> bool {
+    let text = text.trim();
+    text.starts_with("<!--") && text.ends_with("-->")
+}
+
+// The text between `<!--` and `-->`, with the line's indentation kept in
+// front.
+fn comment_body(text: &str) -> String {
+    let trimmed = text.trim_start();
+    let indent = &text[..text.len() - trimmed.len()];
+    let body = trimmed.strip_prefix("<!--").unwrap_or(trimmed);
+    let body = body.trim_end();
+    let body = body.strip_suffix("-->").unwrap_or(body);
+    format!("{}{}", indent, body.trim())
+}
+
+// The task a `comment_task` line came from, if it holds a checkbox line.
+fn commented_task(text: &str) -> Option<Task> {
+    parse_task(&comment_body(text))
+}
+
+fn uncomment_task(text: &str) -> Task {
+    commented_task(text).unwrap_or_else(|| {
+        let body = comment_body(text);
+        Task {
+            indent: String::new(),
+            bullet: "-".to_string(),
+            completed: false,
+            mark: 'x',
+            text: body.trim().to_string(),
+            folded: false,
+            file_indent: None,
+        }
+    })
+}
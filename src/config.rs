@@ -0,0 +1,584 @@
+use std::env;
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use crate::markdown::MarkdownStyle;
+
+// User settings read from config.toml. Only a small TOML subset is understood:
+// `[table]` headers and `key = value` pairs with string, bool, or integer values.
+#[derive(Debug, Clone)]
+pub struct Config {
+    pub general: GeneralConfig,
+    pub keys: KeysConfig,
+    pub format: FormatConfig,
+    pub navigation: NavigationConfig,
+    pub inbox: InboxConfig,
+    pub display: DisplayConfig,
+    pub save: SaveConfig,
+    pub edit: EditConfig,
+    pub daily: DailyConfig,
+    pub lists: ListsConfig,
+    pub archive: ArchiveConfig,
+    pub done: DoneConfig,
+    pub bulk: BulkConfig,
+    // Per-file sort orders reapplied on every save, keyed by file name or path.
+    pub sort: Vec<(String, Vec<SortKey>)>,
+}
+
+// Startup behavior.
+#[derive(Debug, Clone)]
+pub struct GeneralConfig {
+    // Opened when no path is given and $LAZYTODO_FILE is unset.
+    pub file: String,
+    // Write a debug log to lazytodo.log, as with --logs.
+    pub logs: bool,
+}
+
+// Normal-mode keys moved with `[keys] action = "key"`, stored as
+// (pressed key, built-in key) pairs.
+#[derive(Debug, Clone, Default)]
+pub struct KeysConfig {
+    pub remap: Vec<(char, char)>,
+}
+
+// Normal-mode commands that can be rebound, by name and built-in key.
+pub const KEY_ACTIONS: [(&str, char); 51] = [
+    ("down", 'j'),
+    ("up", 'k'),
+    ("first", 'g'),
+    ("last", 'G'),
+    ("toggle", ' '),
+    ("triage", 't'),
+    ("toggle_and_file", 'X'),
+    ("toggle_subtasks", 'A'),
+    ("move_down", 'J'),
+    ("move_up", 'K'),
+    ("delete", 'd'),
+    ("yank", 'y'),
+    ("paste", 'p'),
+    ("paste_above", 'P'),
+    ("duplicate", 'Y'),
+    ("undo", 'u'),
+    ("search", '/'),
+    ("next_match", 'n'),
+    ("prev_match", 'N'),
+    ("command", ':'),
+    ("edit_external", 'e'),
+    ("edit_file", 'E'),
+    ("edit_inline", 'i'),
+    ("focus", 'F'),
+    ("tags", '%'),
+    ("due_date", '@'),
+    ("color", 'c'),
+    ("insert_below", 'o'),
+    ("insert_above", 'O'),
+    ("insert_section", 'S'),
+    ("daily", 'T'),
+    ("separator", '-'),
+    ("comment_out", '#'),
+    ("uncomment", '+'),
+    ("show_comments", 'H'),
+    ("filter", 'f'),
+    ("split_view", '|'),
+    ("visual", 'V'),
+    ("mark", 'm'),
+    ("invert_all", '~'),
+    ("reset_all", 'C'),
+    ("format", '='),
+    ("bullets", 'B'),
+    ("sort_priority", 's'),
+    ("sort_done", 'D'),
+    ("inbox", 'I'),
+    ("move_to_other_buffer", 'M'),
+    ("reload", 'r'),
+    ("force_reload", 'R'),
+    ("quit", 'q'),
+    ("help", '?'),
+];
+
+impl KeysConfig {
+    // The built-in key a press stands for. None when the pressed key's own
+    // command was moved elsewhere and nothing was bound in its place.
+    pub fn translate(&self, pressed: char) -> Option<char> {
+        if let Some(&(_, builtin)) = self.remap.iter().find(|(key, _)| *key == pressed) {
+            return Some(builtin);
+        }
+        if self.remap.iter().any(|&(_, builtin)| builtin == pressed) {
+            return None;
+        }
+        Some(pressed)
+    }
+
+    // The key that runs the built-in command `builtin`: the key bound to it,
+    // else its own key unless that was given to another action.
+    pub fn key_for(&self, builtin: char) -> Option<char> {
+        if let Some(&(pressed, _)) = self.remap.iter().find(|(_, b)| *b == builtin) {
+            return Some(pressed);
+        }
+        if self.remap.iter().any(|&(pressed, _)| pressed == builtin) {
+            return None;
+        }
+        Some(builtin)
+    }
+}
+
+// Which normalizations the format command applies.
+#[derive(Debug, Clone)]
+pub struct FormatConfig {
+    pub bullets: bool,
+    pub bullet: String,
+    pub indent: bool,
+    // Spaces per nesting level for files that don't show their own yet.
+    pub indent_width: usize,
+    pub trailing_whitespace: bool,
+    // Number each run of `1.`/`1)` items consecutively from its first item.
+    pub numbering: bool,
+    // Written into the checkbox of a task as it's completed: `x` or `X`.
+    pub done_mark: char,
+}
+
+impl FormatConfig {
+    pub fn indent_unit(&self) -> String {
+        " ".repeat(self.indent_width)
+    }
+}
+
+// Cursor movement behavior.
+#[derive(Debug, Clone)]
+pub struct NavigationConfig {
+    // Whether ctrl+n/ctrl+p wrap around the ends of the list.
+    pub wrap: bool,
+}
+
+// Location of the scratch inbox toggled with `I`.
+#[derive(Debug, Clone, Default)]
+pub struct InboxConfig {
+    // Defaults to inbox.md next to the opened file.
+    pub path: Option<PathBuf>,
+}
+
+// Layout of the task list.
+#[derive(Debug, Clone)]
+pub struct DisplayConfig {
+    // Wrap tasks at this many columns even on wider terminals; 0 disables the cap.
+    pub max_width: usize,
+    // Show `@due` dates as "in 2 days" instead of the stored date at startup.
+    pub relative_dates: bool,
+    // Shown instead of the list when the file has no tasks.
+    pub empty_message: String,
+    // Add a few quick-start key hints under the empty-state message.
+    pub empty_hints: bool,
+    // Style **bold**, *italic* and `code` in task text; off shows it raw.
+    pub markdown: bool,
+    // Colors for styled markdown; --style or $LAZYTODO_STYLE override it.
+    pub style: MarkdownStyle,
+    // Seconds a status message stays in the footer; 0 keeps it until the next.
+    pub status_timeout: u64,
+    // Flag tasks whose text runs past this many characters; 0 disables it.
+    pub task_length: usize,
+    // Add overall progress to the header, e.g. "— 62% (18/29)".
+    pub header_progress: bool,
+}
+
+// Safety checks run before writing the file.
+#[derive(Debug, Clone)]
+pub struct SaveConfig {
+    // Back up the file and warn when a save would drop or rewrite lines.
+    pub check_data_loss: bool,
+}
+
+// Inline editing behavior.
+#[derive(Debug, Clone, Default)]
+pub struct EditConfig {
+    // What ctrl+c/ctrl+q do while editing a line.
+    pub on_quit: QuitAction,
+}
+
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum QuitAction {
+    // Keep the edit, then quit.
+    #[default]
+    Save,
+    // Drop the edit, then quit.
+    Discard,
+    // Stay in edit mode.
+    Ignore,
+}
+
+// Daily log sections added with `T`.
+#[derive(Debug, Clone)]
+pub struct DailyConfig {
+    // Section title pattern using %Y, %m and %d.
+    pub format: String,
+    // Add new day sections at the top of the file instead of the bottom.
+    pub top: bool,
+}
+
+// Section titles `X` files tasks into when toggling them.
+#[derive(Debug, Clone)]
+pub struct ListsConfig {
+    pub todo: String,
+    pub done: String,
+}
+
+// Automatic archiving of old completed tasks.
+#[derive(Debug, Clone)]
+pub struct ArchiveConfig {
+    // Move tasks completed more than this many days ago into `section` at
+    // startup; 0 turns archiving (and `@done` stamping) off.
+    pub after_days: usize,
+    pub section: String,
+}
+
+// `@done(...)` stamps on completed tasks.
+#[derive(Debug, Clone)]
+pub struct DoneConfig {
+    // Stamp tasks when they're completed and drop the stamp when reopened.
+    // Archiving stamps tasks whatever this says.
+    pub stamp: bool,
+    // Stamp date pattern using %Y, %m and %d. Archiving only reads
+    // YYYY-MM-DD stamps.
+    pub format: String,
+}
+
+// Commands that rewrite many lines at once (`=`, `~`, `C`).
+#[derive(Debug, Clone, Default)]
+pub struct BulkConfig {
+    // Show a diff of the change and apply it only after y.
+    pub preview: bool,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum SortKey {
+    Incomplete,
+    Priority,
+    Due,
+}
+
+impl Default for Config {
+    fn default() -> Self {
+        Self {
+            general: GeneralConfig {
+                file: "todo.md".to_string(),
+                logs: false,
+            },
+            keys: KeysConfig::default(),
+            format: FormatConfig {
+                bullets: true,
+                bullet: "-".to_string(),
+                indent: true,
+                indent_width: 4,
+                trailing_whitespace: true,
+                numbering: true,
+                done_mark: 'x',
+            },
+            navigation: NavigationConfig { wrap: false },
+            inbox: InboxConfig::default(),
+            display: DisplayConfig {
+                max_width: 0,
+                relative_dates: false,
+                empty_message: "No tasks found. Press 'o' to create one.".to_string(),
+                empty_hints: false,
+                markdown: true,
+                style: MarkdownStyle::Auto,
+                status_timeout: 3,
+                task_length: 0,
+                header_progress: false,
+            },
+            save: SaveConfig {
+                check_data_loss: true,
+            },
+            edit: EditConfig::default(),
+            daily: DailyConfig {
+                format: "%Y-%m-%d".to_string(),
+                top: true,
+            },
+            lists: ListsConfig {
+                todo: "To Do".to_string(),
+                done: "Done".to_string(),
+            },
+            archive: ArchiveConfig {
+                after_days: 0,
+                section: "Archive".to_string(),
+            },
+            done: DoneConfig {
+                stamp: false,
+                format: "%Y-%m-%d".to_string(),
+            },
+            bulk: BulkConfig::default(),
+            sort: Vec::new(),
+        }
+    }
+}
+
+impl Config {
+    pub fn sort_for(&self, path: &Path) -> Option<&[SortKey]> {
+        let name = path.file_name().and_then(|s| s.to_str());
+        self.sort
+            .iter()
+            .find(|(key, _)| Some(key.as_str()) == name || expand_home(key).as_path() == path)
+            .map(|(_, keys)| keys.as_slice())
+    }
+}
+
+#[derive(Debug, Clone, PartialEq, Eq)]
+enum Value {
+    Str(String),
+    Bool(bool),
+    Int(i64),
+}
+
+pub fn config_path() -> Option<PathBuf> {
+    if let Some(dir) = env::var_os("XDG_CONFIG_HOME").filter(|d| !d.is_empty()) {
+        return Some(PathBuf::from(dir).join("lazytodo").join("config.toml"));
+    }
+    env::var_os("HOME").map(|home| {
+        PathBuf::from(home)
+            .join(".config")
+            .join("lazytodo")
+            .join("config.toml")
+    })
+}
+
+// Load the config file, falling back to defaults when it doesn't exist.
+pub fn load_config() -> Result<Config, String> {
+    let Some(path) = config_path() else {
+        return Ok(Config::default());
+    };
+    if !path.exists() {
+        return Ok(Config::default());
+    }
+    load_config_from(&path)
+}
+
+pub fn load_config_from(path: &Path) -> Result<Config, String> {
+    let data = fs::read_to_string(path).map_err(|e| format!("{}: {}", path.display(), e))?;
+    parse_config(&data).map_err(|e| format!("{}: {}", path.display(), e))
+}
+
+fn parse_config(data: &str) -> Result<Config, String> {
+    let mut config = Config::default();
+    let mut table = String::new();
+
+    for (i, raw) in data.lines().enumerate() {
+        let line_no = i + 1;
+        let line = strip_comment(raw).trim();
+        if line.is_empty() {
+            continue;
+        }
+        if let Some(rest) = line.strip_prefix('[') {
+            let Some(name) = rest.strip_suffix(']') else {
+                return Err(format!("line {}: malformed table header", line_no));
+            };
+            table = name.trim().to_string();
+            continue;
+        }
+        let Some((key, value)) = line.split_once('=') else {
+            return Err(format!("line {}: expected key = value", line_no));
+        };
+        let key = key.trim().trim_matches('"');
+        let value = parse_value(value.trim()).map_err(|e| format!("line {}: {}", line_no, e))?;
+        apply_setting(&mut config, &table, key, value)
+            .map_err(|e| format!("line {}: {}", line_no, e))?;
+    }
+
+    Ok(config)
+}
+
+fn apply_setting(config: &mut Config, table: &str, key: &str, value: Value) -> Result<(), String> {
+    match (table, key) {
+        ("general", "file") => config.general.file = expect_str(key, value)?,
+        ("general", "logs") => config.general.logs = expect_bool(key, value)?,
+        ("keys", action) => {
+            let Some(&(_, builtin)) = KEY_ACTIONS.iter().find(|(name, _)| *name == action) else {
+                return Err(format!("unknown setting {}", qualified(table, key)));
+            };
+            let pressed = expect_key(key, value)?;
+            if let Some((_, other)) = config.keys.remap.iter().find(|(k, _)| *k == pressed) {
+                let other = KEY_ACTIONS
+                    .iter()
+                    .find(|(_, b)| b == other)
+                    .map_or("", |a| a.0);
+                return Err(format!(
+                    "key {:?} bound to both {} and {}",
+                    pressed, other, action
+                ));
+            }
+            config.keys.remap.push((pressed, builtin));
+        }
+        ("format", "bullets") => config.format.bullets = expect_bool(key, value)?,
+        ("format", "bullet") => {
+            let bullet = expect_str(key, value)?;
+            if bullet != "-" && bullet != "*" {
+                return Err(format!("bullet must be \"-\" or \"*\", got {:?}", bullet));
+            }
+            config.format.bullet = bullet;
+        }
+        ("format", "done_mark") => {
+            let mark = expect_str(key, value)?;
+            config.format.done_mark = match mark.as_str() {
+                "x" => 'x',
+                "X" => 'X',
+                _ => return Err(format!("done_mark must be \"x\" or \"X\", got {:?}", mark)),
+            };
+        }
+        ("format", "indent") => config.format.indent = expect_bool(key, value)?,
+        ("format", "indent_width") => {
+            let width = expect_usize(key, value)?;
+            if !(1..=8).contains(&width) {
+                return Err(format!("indent_width must be 1 to 8, got {}", width));
+            }
+            config.format.indent_width = width;
+        }
+        ("format", "trailing_whitespace") => {
+            config.format.trailing_whitespace = expect_bool(key, value)?
+        }
+        ("format", "numbering") => config.format.numbering = expect_bool(key, value)?,
+        ("navigation", "wrap") => config.navigation.wrap = expect_bool(key, value)?,
+        ("inbox", "path") => config.inbox.path = Some(expand_home(&expect_str(key, value)?)),
+        ("display", "max_width") => config.display.max_width = expect_usize(key, value)?,
+        ("display", "empty_message") => config.display.empty_message = expect_str(key, value)?,
+        ("display", "empty_hints") => config.display.empty_hints = expect_bool(key, value)?,
+        ("display", "relative_dates") => config.display.relative_dates = expect_bool(key, value)?,
+        ("display", "markdown") => config.display.markdown = expect_bool(key, value)?,
+        ("display", "style") => {
+            let name = expect_str(key, value)?;
+            config.display.style = MarkdownStyle::parse(&name).ok_or_else(|| {
+                format!(
+                    "style must be \"auto\", \"dark\", \"light\" or \"notty\", got {:?}",
+                    name
+                )
+            })?;
+        }
+        ("display", "status_timeout") => {
+            config.display.status_timeout = expect_usize(key, value)? as u64
+        }
+        ("display", "header_progress") => config.display.header_progress = expect_bool(key, value)?,
+        ("display", "task_length") => config.display.task_length = expect_usize(key, value)?,
+        ("save", "check_data_loss") => config.save.check_data_loss = expect_bool(key, value)?,
+        ("edit", "on_quit") => {
+            config.edit.on_quit = match expect_str(key, value)?.as_str() {
+                "save" => QuitAction::Save,
+                "discard" => QuitAction::Discard,
+                "ignore" => QuitAction::Ignore,
+                other => {
+                    return Err(format!(
+                        "on_quit must be \"save\", \"discard\" or \"ignore\", got {:?}",
+                        other
+                    ))
+                }
+            }
+        }
+        ("daily", "format") => config.daily.format = expect_str(key, value)?,
+        ("daily", "position") => {
+            config.daily.top = match expect_str(key, value)?.as_str() {
+                "top" => true,
+                "bottom" => false,
+                other => {
+                    return Err(format!(
+                        "position must be \"top\" or \"bottom\", got {:?}",
+                        other
+                    ))
+                }
+            }
+        }
+        ("lists", "todo") => config.lists.todo = expect_str(key, value)?,
+        ("lists", "done") => config.lists.done = expect_str(key, value)?,
+        ("archive", "after_days") => config.archive.after_days = expect_usize(key, value)?,
+        ("archive", "section") => config.archive.section = expect_str(key, value)?,
+        ("done", "stamp") => config.done.stamp = expect_bool(key, value)?,
+        ("done", "format") => config.done.format = expect_str(key, value)?,
+        ("bulk", "preview") => config.bulk.preview = expect_bool(key, value)?,
+        ("sort", file) => {
+            let keys = parse_sort_keys(&expect_str(key, value)?)?;
+            config.sort.push((file.to_string(), keys));
+        }
+        _ => return Err(format!("unknown setting {}", qualified(table, key))),
+    }
+    Ok(())
+}
+
+fn qualified(table: &str, key: &str) -> String {
+    if table.is_empty() {
+        key.to_string()
+    } else {
+        format!("{}.{}", table, key)
+    }
+}
+
+fn parse_sort_keys(raw: &str) -> Result<Vec<SortKey>, String> {
+    raw.split(',')
+        .map(|key| match key.trim() {
+            "incomplete" => Ok(SortKey::Incomplete),
+            "priority" => Ok(SortKey::Priority),
+            "due" => Ok(SortKey::Due),
+            other => Err(format!("unknown sort key {:?}", other)),
+        })
+        .collect()
+}
+
+fn expand_home(path: &str) -> PathBuf {
+    match (path.strip_prefix("~/"), env::var_os("HOME")) {
+        (Some(rest), Some(home)) => PathBuf::from(home).join(rest),
+        _ => PathBuf::from(path),
+    }
+}
+
+fn strip_comment(line: &str) -> &str {
+    let mut in_string = false;
+    for (i, ch) in line.char_indices() {
+        match ch {
+            '"' => in_string = !in_string,
+            '#' if !in_string => return &line[..i],
+            _ => {}
+        }
+    }
+    line
+}
+
+fn parse_value(raw: &str) -> Result<Value, String> {
+    if let Some(rest) = raw.strip_prefix('"') {
+        return match rest.strip_suffix('"') {
+            Some(inner) => Ok(Value::Str(inner.to_string())),
+            None => Err("unterminated string".to_string()),
+        };
+    }
+    match raw {
+        "true" => return Ok(Value::Bool(true)),
+        "false" => return Ok(Value::Bool(false)),
+        _ => {}
+    }
+    raw.parse::<i64>()
+        .map(Value::Int)
+        .map_err(|_| format!("invalid value {}", raw))
+}
+
+fn expect_bool(key: &str, value: Value) -> Result<bool, String> {
+    match value {
+        Value::Bool(b) => Ok(b),
+        _ => Err(format!("{} must be true or false", key)),
+    }
+}
+
+fn expect_usize(key: &str, value: Value) -> Result<usize, String> {
+    match value {
+        Value::Int(n) if n >= 0 => Ok(n as usize),
+        _ => Err(format!("{} must be a non-negative integer", key)),
+    }
+}
+
+fn expect_key(key: &str, value: Value) -> Result<char, String> {
+    let raw = expect_str(key, value)?;
+    let mut chars = raw.chars();
+    match (chars.next(), chars.next()) {
+        (Some(c), None) => Ok(c),
+        _ => Err(format!("{} must be a single character, got {:?}", key, raw)),
+    }
+}
+
+fn expect_str(key: &str, value: Value) -> Result<String, String> {
+    match value {
+        Value::Str(s) => Ok(s),
+        _ => Err(format!("{} must be a string", key)),
+    }
+}
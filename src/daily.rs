@@ -0,0 +1,46 @@
+use crate::date::Date;
+use crate::model::{App, LineItem};
+
+impl App {
+    // Jump to today's `## YYYY-MM-DD` section, creating it first if needed,
+    // and start a new task at its end.
+    pub fn open_daily_log(&mut self) {
+        let title = Date::today().format(&self.config.daily.format);
+        let existing = self
+            .lines
+            .iter()
+            .position(|line| matches!(line, LineItem::Section { title: t, .. } if *t == title));
+
+        let header = match existing {
+            Some(header) => header,
+            None => {
+                // "Top" means above the first section, so loose tasks at the
+                // start of the file don't end up under the new header.
+                let first_section = self.lines.iter().position(|line| line.is_section());
+                let idx = match first_section {
+                    Some(idx) if self.config.daily.top => idx,
+                    _ => self.lines.len(),
+                };
+                self.save_undo_state();
+                self.clear_selection();
+                self.lines.insert(
+                    idx,
+                    LineItem::Section {
+                        title: title.clone(),
+                        collapsed: false,
+                    },
+                );
+                self.save_and_set_status(&format!("Added {}", title));
+                idx
+            }
+        };
+
+        if let LineItem::Section { collapsed, .. } = &mut self.lines[header] {
+            *collapsed = false;
+        }
+        self.outline = false;
+        let end = self.section_end(header);
+        self.cursor = header;
+        self.start_insert_task_at(end);
+    }
+}
@@ -0,0 +1,136 @@
+use std::fmt;
+use std::time::{SystemTime, UNIX_EPOCH};
+
+// Calendar dates for `@due(...)` and friends, without a date dependency.
+// "Today" is taken in UTC since std offers no time zone lookup.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord)]
+pub struct Date {
+    pub year: i32,
+    pub month: u32,
+    pub day: u32,
+}
+
+impl Date {
+    pub fn today() -> Self {
+        let secs = SystemTime::now()
+            .duration_since(UNIX_EPOCH)
+            .map(|d| d.as_secs())
+            .unwrap_or(0);
+        Self::from_days((secs / 86_400) as i64)
+    }
+
+    // Parse a `YYYY-MM-DD` date, rejecting days that don't exist.
+    pub fn parse(raw: &str) -> Option<Self> {
+        let mut parts = raw.trim().splitn(3, '-');
+        let year: i32 = parts.next()?.parse().ok()?;
+        let month: u32 = parts.next()?.parse().ok()?;
+        let day: u32 = parts.next()?.parse().ok()?;
+        if !(1..=12).contains(&month) || day == 0 || day > days_in_month(year, month) {
+            return None;
+        }
+        Some(Self { year, month, day })
+    }
+
+    // Days since 1970-01-01 (Howard Hinnant's days_from_civil).
+    pub fn to_days(self) -> i64 {
+        let y = i64::from(self.year) - i64::from(self.month <= 2);
+        let era = y.div_euclid(400);
+        let yoe = y - era * 400;
+        let m = i64::from(self.month);
+        let doy = (153 * (if m > 2 { m - 3 } else { m + 9 }) + 2) / 5 + i64::from(self.day) - 1;
+        let doe = yoe * 365 + yoe / 4 - yoe / 100 + doy;
+        era * 146_097 + doe - 719_468
+    }
+
+    // Date for a count of days since 1970-01-01 (Howard Hinnant's civil_from_days).
+    pub fn from_days(days: i64) -> Self {
+        let z = days + 719_468;
+        let era = z.div_euclid(146_097);
+        let doe = z - era * 146_097;
+        let yoe = (doe - doe / 1460 + doe / 36_524 - doe / 146_096) / 365;
+        let doy = doe - (365 * yoe + yoe / 4 - yoe / 100);
+        let mp = (5 * doy + 2) / 153;
+        let day = (doy - (153 * mp + 2) / 5 + 1) as u32;
+        let month = if mp < 10 { mp + 3 } else { mp - 9 } as u32;
+        let year = (yoe + era * 400 + i64::from(month <= 2)) as i32;
+        Self { year, month, day }
+    }
+
+    // Format with `%Y`, `%m` and `%d` placeholders; `%%` is a literal percent.
+    pub fn format(self, pattern: &str) -> String {
+        let mut out = String::new();
+        let mut chars = pattern.chars();
+        while let Some(ch) = chars.next() {
+            if ch != '%' {
+                out.push(ch);
+                continue;
+            }
+            match chars.next() {
+                Some('Y') => out.push_str(&format!("{:04}", self.year)),
+                Some('m') => out.push_str(&format!("{:02}", self.month)),
+                Some('d') => out.push_str(&format!("{:02}", self.day)),
+                Some('%') => out.push('%'),
+                Some(other) => {
+                    out.push('%');
+                    out.push(other);
+                }
+                None => out.push('%'),
+            }
+        }
+        out
+    }
+
+    pub fn add_days(self, days: i64) -> Self {
+        Self::from_days(self.to_days() + days)
+    }
+
+    // Same day in a month `months` away, clamped to that month's length.
+    pub fn add_months(self, months: i32) -> Self {
+        let index = self.year * 12 + self.month as i32 - 1 + months;
+        let year = index.div_euclid(12);
+        let month = index.rem_euclid(12) as u32 + 1;
+        let day = self.day.min(days_in_month(year, month));
+        Self { year, month, day }
+    }
+
+    // 0 for Monday through 6 for Sunday.
+    pub fn weekday(self) -> u32 {
+        // 1970-01-01 was a Thursday.
+        (self.to_days() + 3).rem_euclid(7) as u32
+    }
+
+    pub fn days_in_month(self) -> u32 {
+        days_in_month(self.year, self.month)
+    }
+
+    // Signed number of days from `other` to this date.
+    pub fn days_since(self, other: Date) -> i64 {
+        self.to_days() - other.to_days()
+    }
+
+    // Human phrasing relative to `today`: "today", "in 2 days", "3 days ago".
+    pub fn relative_to(self, today: Date) -> String {
+        match self.days_since(today) {
+            0 => "today".to_string(),
+            1 => "tomorrow".to_string(),
+            -1 => "yesterday".to_string(),
+            n if n > 0 => format!("in {} days", n),
+            n => format!("{} days ago", -n),
+        }
+    }
+}
+
+impl fmt::Display for Date {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "{:04}-{:02}-{:02}", self.year, self.month, self.day)
+    }
+}
+
+fn days_in_month(year: i32, month: u32) -> u32 {
+    match month {
+        2 if (year % 4 == 0 && year % 100 != 0) || year % 400 == 0 => 29,
+        2 => 28,
+        4 | 6 | 9 | 11 => 30,
+        _ => 31,
+    }
+}
@@ -0,0 +1,105 @@
+use crate::date::Date;
+use crate::keys::Key;
+
+const MONTHS: [&str; 12] = [
+    "January",
+    "February",
+    "March",
+    "April",
+    "May",
+    "June",
+    "July",
+    "August",
+    "September",
+    "October",
+    "November",
+    "December",
+];
+
+const REVERSE_ON: &str = "\x1b[7m";
+const UNDERLINE_ON: &str = "\x1b[4m";
+const STYLE_OFF: &str = "\x1b[0m";
+
+// A month calendar for choosing a date with the keyboard.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct DatePicker {
+    pub selected: Date,
+    pub today: Date,
+}
+
+// What the caller should do after a key press.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum PickerAction {
+    None,
+    Pick(Date),
+    Clear,
+    Cancel,
+}
+
+impl DatePicker {
+    pub fn new(selected: Date, today: Date) -> Self {
+        Self { selected, today }
+    }
+
+    pub fn handle_key(&mut self, key: Key) -> PickerAction {
+        match key {
+            Key::Esc | Key::Char('q') | Key::Ctrl('c') => return PickerAction::Cancel,
+            Key::Enter | Key::Char(' ') => return PickerAction::Pick(self.selected),
+            Key::Char('x') => return PickerAction::Clear,
+            Key::Char('h') | Key::Left => self.selected = self.selected.add_days(-1),
+            Key::Char('l') | Key::Right => self.selected = self.selected.add_days(1),
+            Key::Char('k') | Key::Up => self.selected = self.selected.add_days(-7),
+            Key::Char('j') | Key::Down => self.selected = self.selected.add_days(7),
+            Key::Char('H') | Key::Char('<') => self.selected = self.selected.add_months(-1),
+            Key::Char('L') | Key::Char('>') => self.selected = self.selected.add_months(1),
+            Key::Char('t') => return PickerAction::Pick(self.today),
+            Key::Char('m') => return PickerAction::Pick(self.today.add_days(1)),
+            Key::Char('w') => return PickerAction::Pick(self.today.add_days(7)),
+            _ => {}
+        }
+        PickerAction::None
+    }
+
+    // The month around the selected date, Monday first. The selection is
+    // shown in reverse video and today is underlined.
+    pub fn render(&self) -> Vec<String> {
+        let first = Date {
+            day: 1,
+            ..self.selected
+        };
+        let mut lines = vec![
+            format!(
+                "{} {}",
+                MONTHS[self.selected.month as usize - 1],
+                self.selected.year
+            ),
+            "Mo Tu We Th Fr Sa Su".to_string(),
+        ];
+
+        let mut row = "   ".repeat(first.weekday() as usize);
+        for day in 1..=first.days_in_month() {
+            let date = Date { day, ..first };
+            let cell = format!("{:>2}", day);
+            if date == self.selected {
+                row.push_str(&format!("{}{}{}", REVERSE_ON, cell, STYLE_OFF));
+            } else if date == self.today {
+                row.push_str(&format!("{}{}{}", UNDERLINE_ON, cell, STYLE_OFF));
+            } else {
+                row.push_str(&cell);
+            }
+            if date.weekday() == 6 {
+                lines.push(row);
+                row = String::new();
+            } else {
+                row.push(' ');
+            }
+        }
+        if !row.is_empty() {
+            lines.push(row.trim_end().to_string());
+        }
+
+        lines.push(String::new());
+        lines.push(format!("Selected: {}", self.selected));
+        lines
+    }
+}
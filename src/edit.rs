@@ -1,4 +1,4 @@
-use crate::model::{App, EditIntent, EditTarget, LineItem, Mode, Task, INDENT_LEVELS};
+use crate::model::{App, EditIntent, EditTarget, LineItem, Mode, Task, MAX_INDENT_LEVEL};
 
 impl App {
     pub fn start_edit_current(&mut self) {
@@ -8,7 +8,13 @@ impl App {
         match self.lines.get(self.cursor) {
             Some(LineItem::Section { .. }) => self.start_edit_section(),
             Some(LineItem::Task(_)) => self.start_edit_task(),
-            None => {}
+            Some(
+                LineItem::File { .. }
+                | LineItem::Rule
+                | LineItem::Comment { .. }
+                | LineItem::Raw { .. },
+            )
+            | None => {}
         }
     }
 
@@ -28,7 +34,7 @@ impl App {
         self.input_placeholder = "Describe the task".to_string();
         self.text_input.set_value(text);
         self.edit_index = Some(self.cursor);
-        self.status_message = "Editing current task".to_string();
+        self.set_status("Editing current task");
     }
 
     pub fn start_edit_section(&mut self) {
@@ -36,7 +42,7 @@ impl App {
             return;
         }
         let title = match self.lines.get(self.cursor) {
-            Some(LineItem::Section { title }) => title.clone(),
+            Some(LineItem::Section { title, .. }) => title.clone(),
             _ => return,
         };
 
@@ -47,7 +53,7 @@ impl App {
         self.input_placeholder = "Section title".to_string();
         self.text_input.set_value(title);
         self.edit_index = Some(self.cursor);
-        self.status_message = "Editing section".to_string();
+        self.set_status("Editing section");
     }
 
     pub fn start_insert_task_at(&mut self, index: usize) {
@@ -66,10 +72,71 @@ impl App {
         self.cursor = self.edit_index.unwrap_or(0);
         self.text_input.reset();
         self.input_placeholder = "Describe the task".to_string();
-        self.status_message = "New task".to_string();
+        self.set_status("New task");
         self.edit_template = template;
     }
 
+    // Insert a `---` separator below the cursor.
+    pub fn insert_rule(&mut self) {
+        let idx = clamp_index(self.insert_below_index(), self.lines.len());
+        self.save_undo_state();
+        self.clear_selection();
+        self.expand_section_containing(idx.saturating_sub(1));
+        self.lines.insert(idx, LineItem::Rule);
+        self.cursor = idx;
+        self.save_and_set_status("Inserted separator");
+    }
+
+    // Swap the current line with the one above (`delta` -1) or below (+1),
+    // keeping the cursor on it. Section headers move alone; file markers and
+    // the ends of the list don't move.
+    pub fn move_line(&mut self, delta: isize) {
+        let Some(target) = self.cursor.checked_add_signed(delta) else {
+            return;
+        };
+        if target >= self.lines.len() || self.lines[self.cursor].is_file() {
+            return;
+        }
+        if self.lines[target].is_file() {
+            return;
+        }
+        let msg = match &self.lines[self.cursor] {
+            LineItem::Section { .. } => "Moved section",
+            _ => "Moved task",
+        };
+
+        self.save_undo_state();
+        self.clear_selection();
+        self.lines.swap(self.cursor, target);
+        self.cursor = target;
+        self.remember_edit(target);
+        self.save_and_set_status(msg);
+    }
+
+    // `gJ`: append the next task's text to the current one, separated by a
+    // space, and drop the next task. Only joins a task to the task below.
+    pub fn join_next_task(&mut self) {
+        let next = match (self.lines.get(self.cursor), self.lines.get(self.cursor + 1)) {
+            (Some(LineItem::Task(_)), Some(LineItem::Task(next))) => next.text.clone(),
+            _ => {
+                self.set_status("Nothing to join");
+                return;
+            }
+        };
+
+        self.save_undo_state();
+        self.clear_selection();
+        self.lines.remove(self.cursor + 1);
+        if let Some(LineItem::Task(task)) = self.lines.get_mut(self.cursor) {
+            task.text = format!("{} {}", task.text.trim_end(), next.trim_start())
+                .trim()
+                .to_string();
+        }
+        self.renumber_list(self.cursor);
+        self.remember_edit(self.cursor);
+        self.save_and_set_status("Joined tasks");
+    }
+
     pub fn start_insert_section_at(&mut self, index: usize) {
         self.clear_selection();
         self.mode = Mode::Edit;
@@ -80,7 +147,7 @@ impl App {
         self.cursor = self.edit_index.unwrap_or(0);
         self.text_input.reset();
         self.input_placeholder = "Section title".to_string();
-        self.status_message = "New section".to_string();
+        self.set_status("New section");
     }
 
     pub fn apply_current_edit(&mut self, value: &str) {
@@ -90,7 +157,7 @@ impl App {
                     if let Some(idx) = self.edit_index {
                         if matches!(self.lines.get(idx), Some(LineItem::Section { .. })) {
                             self.save_undo_state();
-                            if let Some(LineItem::Section { title }) = self.lines.get_mut(idx) {
+                            if let Some(LineItem::Section { title, .. }) = self.lines.get_mut(idx) {
                                 *title = value.to_string();
                             }
                         }
@@ -99,6 +166,7 @@ impl App {
                 EditIntent::Insert => {
                     let new_section = LineItem::Section {
                         title: value.to_string(),
+                        collapsed: false,
                     };
                     let idx = clamp_index(self.insert_index.unwrap_or(0), self.lines.len());
                     self.save_undo_state();
@@ -113,6 +181,7 @@ impl App {
                         if let Some(LineItem::Task(task)) = self.lines.get_mut(idx) {
                             task.text = value.to_string();
                         }
+                        self.remember_edit(idx);
                     }
                 }
                 EditIntent::Insert => {
@@ -121,16 +190,47 @@ impl App {
                         indent: self.edit_template.indent.clone(),
                         bullet: self.edit_template.bullet.clone(),
                         completed: false,
+                        mark: 'x',
                         text: value.to_string(),
+                        folded: false,
+                        file_indent: None,
                     });
+                    self.expand_section_containing(idx.saturating_sub(1));
                     self.lines.insert(idx, new_task);
+                    self.renumber_list(idx);
                     self.cursor = idx;
+                    self.remember_edit(idx);
                 }
                 EditIntent::None => {}
             },
         }
     }
 
+    // Ctrl+Enter while editing a task: keep the text before the cursor on
+    // it and carry the rest into a new task below, still being edited. With
+    // the cursor at either end there's nothing to split off.
+    pub(crate) fn split_edited_task(&mut self) {
+        if self.edit_target != EditTarget::Task {
+            return;
+        }
+        let (before, after) = self.text_input.split_at_cursor();
+        let (before, after) = (
+            before.trim_end().to_string(),
+            after.trim_start().to_string(),
+        );
+        if before.trim().is_empty() || after.is_empty() {
+            self.set_status("Move the cursor into the text to split it");
+            return;
+        }
+
+        self.apply_current_edit(&before);
+        self.save_and_set_status("");
+        self.start_insert_task_at(self.cursor + 1);
+        self.text_input.set_value(after);
+        self.text_input.move_home();
+        self.set_status("Split task");
+    }
+
     pub fn exit_edit_mode(&mut self) {
         self.mode = Mode::Normal;
         self.edit_intent = EditIntent::None;
@@ -138,11 +238,51 @@ impl App {
         self.pending_reload = false;
         self.edit_index = None;
         self.insert_index = None;
-        self.cursor = clamp_cursor(self.cursor, self.lines.len());
+        self.clamp_cursor_to_visible();
         self.text_input.reset();
         self.normalize_selection();
     }
 
+    // Indent (`delta` 1) or outdent (-1) the current task, or every task in
+    // the selection, in normal mode. Section headers are left alone.
+    pub fn shift_indent(&mut self, delta: isize) {
+        let targets = if self.has_selection() {
+            self.selected_indices()
+        } else {
+            vec![self.cursor]
+        };
+        let changes: Vec<(usize, usize)> = targets
+            .into_iter()
+            .filter_map(|i| match self.lines.get(i) {
+                Some(LineItem::Task(task)) => {
+                    let level = get_indent_level(&task.indent, &self.indent_unit);
+                    let new_level = level.saturating_add_signed(delta).min(MAX_INDENT_LEVEL);
+                    (new_level != level).then_some((i, new_level))
+                }
+                _ => None,
+            })
+            .collect();
+        if changes.is_empty() {
+            self.set_status("Nothing to indent");
+            return;
+        }
+
+        self.save_undo_state();
+        self.clear_selection();
+        for &(i, level) in &changes {
+            if let Some(LineItem::Task(task)) = self.lines.get_mut(i) {
+                task.indent = indent_for(level, &self.indent_unit);
+            }
+        }
+        let verb = if delta > 0 { "Indented" } else { "Outdented" };
+        let msg = if changes.len() == 1 {
+            format!("{} task", verb)
+        } else {
+            format!("{} {} tasks", verb, changes.len())
+        };
+        self.save_and_set_status(&msg);
+    }
+
     pub fn change_indent(&mut self, delta: isize) {
         if self.edit_target != EditTarget::Task {
             return;
@@ -164,16 +304,11 @@ impl App {
             return;
         };
 
-        let current_level = get_indent_level(&current_indent);
-        let mut new_level = current_level as isize + delta;
-        if new_level < 0 {
-            new_level = 0;
-        }
-        if new_level as usize >= INDENT_LEVELS.len() {
-            new_level = (INDENT_LEVELS.len() - 1) as isize;
-        }
-
-        let new_indent = INDENT_LEVELS[new_level as usize].to_string();
+        let current_level = get_indent_level(&current_indent, &self.indent_unit);
+        let new_level = current_level
+            .saturating_add_signed(delta)
+            .min(MAX_INDENT_LEVEL);
+        let new_indent = indent_for(new_level, &self.indent_unit);
         if self.edit_intent == EditIntent::Update {
             if let Some(idx) = self.edit_index {
                 if let Some(LineItem::Task(task)) = self.lines.get_mut(idx) {
@@ -184,16 +319,90 @@ impl App {
             self.edit_template.indent = new_indent;
         }
     }
+
+    // Number the ordered siblings around the task at `index` consecutively,
+    // so an inserted item doesn't repeat its neighbour's marker.
+    pub(crate) fn renumber_list(&mut self, index: usize) {
+        renumber_run(&mut self.lines, index, &self.indent_unit);
+    }
 }
 
-fn get_indent_level(indent: &str) -> usize {
-    let normalized = indent.replace('\t', "    ");
-    let spaces = normalized.len();
-    let mut level = spaces / 4;
-    if level >= INDENT_LEVELS.len() {
-        level = INDENT_LEVELS.len() - 1;
+// Number the ordered siblings around the task at `index` consecutively,
+// starting from the first one's number. Subtasks in between are skipped.
+// Returns how many markers changed.
+pub fn renumber_run(lines: &mut [LineItem], index: usize, unit: &str) -> usize {
+    let Some(LineItem::Task(task)) = lines.get(index) else {
+        return 0;
+    };
+    if ordered_marker(&task.bullet).is_none() {
+        return 0;
+    }
+    let level = get_indent_level(&task.indent, unit);
+    let sibling = |line: &LineItem| match line {
+        LineItem::Task(task) => Some((
+            get_indent_level(&task.indent, unit),
+            ordered_marker(&task.bullet).is_some(),
+        )),
+        _ => None,
+    };
+
+    let mut start = index;
+    for i in (0..index).rev() {
+        match sibling(&lines[i]) {
+            Some((l, _)) if l > level => {}
+            Some((l, true)) if l == level => start = i,
+            _ => break,
+        }
+    }
+    let mut siblings = Vec::new();
+    for (i, line) in lines.iter().enumerate().skip(start) {
+        match sibling(line) {
+            Some((l, _)) if l > level => {}
+            Some((l, true)) if l == level => siblings.push(i),
+            _ => break,
+        }
+    }
+
+    let first = match &lines[start] {
+        LineItem::Task(task) => ordered_marker(&task.bullet).map_or(1, |(n, _)| n),
+        _ => 1,
+    };
+    let mut changed = 0;
+    for (offset, i) in siblings.into_iter().enumerate() {
+        if let LineItem::Task(task) = &mut lines[i] {
+            if let Some((_, delim)) = ordered_marker(&task.bullet) {
+                let bullet = format!("{}{}", first + offset, delim);
+                if task.bullet != bullet {
+                    task.bullet = bullet;
+                    changed += 1;
+                }
+            }
+        }
     }
-    level
+    changed
+}
+
+// Number and delimiter of an ordered list marker (`3.` -> (3, '.')).
+pub fn ordered_marker(bullet: &str) -> Option<(usize, char)> {
+    let delim = bullet.chars().last().filter(|c| matches!(c, '.' | ')'))?;
+    let number = bullet[..bullet.len() - 1].parse().ok()?;
+    Some((number, delim))
+}
+
+// Nesting level of `indent` in steps of `unit` (e.g. "  " or "\t"). Tabs
+// count as 4 columns, on both sides.
+pub fn get_indent_level(indent: &str, unit: &str) -> usize {
+    let step = indent_width(unit).max(1);
+    (indent_width(indent) / step).min(MAX_INDENT_LEVEL)
+}
+
+// Indentation for `level`, written with `unit`.
+pub fn indent_for(level: usize, unit: &str) -> String {
+    unit.repeat(level.min(MAX_INDENT_LEVEL))
+}
+
+pub fn indent_width(indent: &str) -> usize {
+    indent.replace('\t', "    ").len()
 }
 
 pub fn clamp_cursor(cursor: usize, length: usize) -> usize {
@@ -0,0 +1,142 @@
+use pulldown_cmark::{Event, Options, Parser, Tag, TagEnd};
+
+use crate::edit::get_indent_level;
+use crate::io::detect_indent_unit;
+use crate::markdown::plain_text;
+use crate::model::{task_progress, LineItem};
+
+const HTML_STYLE: &str = "body { font-family: sans-serif; max-width: 40em; margin: 2em auto; }
+ul { list-style: none; padding-left: 0; }
+li.level-1 { margin-left: 1.5em; }
+li.level-2 { margin-left: 3em; }
+li.level-3 { margin-left: 4.5em; }
+li.done { text-decoration: line-through; color: #888; }
+.progress { color: #555; }";
+
+// Standalone HTML page for `lines`: sections as <h2>, tasks as read-only
+// checkboxes with completed ones struck through (`done` class). Comments
+// are left out.
+pub fn export_html(lines: &[LineItem], title: &str) -> String {
+    // With nothing nested every indent is empty, so any unit will do.
+    let unit = detect_indent_unit(lines).unwrap_or_default();
+    let (done, total) = task_progress(lines);
+    let mut out = format!(
+        "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>{title}</title>\n<style>\n{HTML_STYLE}\n</style>\n</head>\n<body>\n<h1>{title}</h1>\n<p class=\"progress\">{done}/{total} done</p>\n",
+        title = escape_html(title),
+    );
+
+    let mut in_list = false;
+    for line in lines {
+        if let LineItem::Task(task) = line {
+            if !in_list {
+                out.push_str("<ul>\n");
+                in_list = true;
+            }
+            let level = get_indent_level(&task.indent, &unit);
+            let (class, checked) = if task.completed {
+                (" done", " checked")
+            } else {
+                ("", "")
+            };
+            out.push_str(&format!(
+                "<li class=\"level-{}{}\"><input type=\"checkbox\" disabled{}> {}</li>\n",
+                level,
+                class,
+                checked,
+                inline_html(&task.text)
+            ));
+            continue;
+        }
+        if in_list {
+            out.push_str("</ul>\n");
+            in_list = false;
+        }
+        match line {
+            LineItem::Section { title, .. } => {
+                out.push_str(&format!("<h2>{}</h2>\n", inline_html(title)))
+            }
+            LineItem::File { path } => {
+                let name = path.file_name().unwrap_or_default().to_string_lossy();
+                out.push_str(&format!("<h1>{}</h1>\n", escape_html(&name)));
+            }
+            LineItem::Rule => out.push_str("<hr>\n"),
+            LineItem::Raw { text } if !text.trim().is_empty() => {
+                out.push_str(&format!("<p>{}</p>\n", inline_html(text.trim())))
+            }
+            _ => {}
+        }
+    }
+    if in_list {
+        out.push_str("</ul>\n");
+    }
+    out.push_str("</body>\n</html>\n");
+    out
+}
+
+// Plain checklist: a progress line, then section titles with their tasks
+// as `[ ]`/`[x]` items, nested two spaces a level, markdown stripped.
+pub fn export_text(lines: &[LineItem], title: &str) -> String {
+    let unit = detect_indent_unit(lines).unwrap_or_default();
+    let (done, total) = task_progress(lines);
+    let mut out = format!("{}: {}/{} done\n", title, done, total);
+    for line in lines {
+        match line {
+            LineItem::Section { title, .. } => {
+                out.push_str(&format!("\n{}\n", plain_text(title)));
+            }
+            LineItem::File { path } => {
+                let name = path.file_name().unwrap_or_default().to_string_lossy();
+                out.push_str(&format!("\n# {}\n", name));
+            }
+            LineItem::Task(task) => {
+                let level = get_indent_level(&task.indent, &unit);
+                let mark = if task.completed { "x" } else { " " };
+                out.push_str(&format!(
+                    "{}[{}] {}\n",
+                    "  ".repeat(level),
+                    mark,
+                    plain_text(&task.text)
+                ));
+            }
+            _ => {}
+        }
+    }
+    out
+}
+
+// Inline markdown (emphasis, strikethrough, code, links) as HTML. Other
+// markup keeps only its text, and raw HTML in the text is escaped.
+fn inline_html(text: &str) -> String {
+    let mut options = Options::empty();
+    options.insert(Options::ENABLE_STRIKETHROUGH);
+
+    let mut out = String::new();
+    for event in Parser::new_ext(text, options) {
+        match event {
+            Event::Start(Tag::Emphasis) => out.push_str("<em>"),
+            Event::End(TagEnd::Emphasis) => out.push_str("</em>"),
+            Event::Start(Tag::Strong) => out.push_str("<strong>"),
+            Event::End(TagEnd::Strong) => out.push_str("</strong>"),
+            Event::Start(Tag::Strikethrough) => out.push_str("<del>"),
+            Event::End(TagEnd::Strikethrough) => out.push_str("</del>"),
+            Event::Start(Tag::Link { dest_url, .. }) => {
+                out.push_str(&format!("<a href=\"{}\">", escape_html(&dest_url)))
+            }
+            Event::End(TagEnd::Link) => out.push_str("</a>"),
+            Event::Code(code) => out.push_str(&format!("<code>{}</code>", escape_html(&code))),
+            Event::Text(text) | Event::Html(text) | Event::InlineHtml(text) => {
+                out.push_str(&escape_html(&text))
+            }
+            Event::SoftBreak | Event::HardBreak => out.push(' '),
+            _ => {}
+        }
+    }
+    out.trim().to_string()
+}
+
+fn escape_html(text: &str) -> String {
+    text.replace('&', "&amp;")
+        .replace('<', "&lt;")
+        .replace('>', "&gt;")
+        .replace('"', "&quot;")
+}
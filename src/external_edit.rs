@@ -7,7 +7,7 @@ use crossterm::terminal::{
     disable_raw_mode, enable_raw_mode, EnterAlternateScreen, LeaveAlternateScreen,
 };
 use crossterm::ExecutableCommand;
-use tempfile::NamedTempFile;
+use tempfile::{Builder, NamedTempFile};
 
 // Temporarily leaves alt-screen and raw mode so external editors can run normally.
 pub struct TerminalSuspend;
@@ -32,15 +32,49 @@ impl Drop for TerminalSuspend {
 }
 
 pub fn edit_in_external_editor(current_text: &str) -> Result<Option<String>, String> {
-    let mut tmp = NamedTempFile::new().map_err(|e| e.to_string())?;
-    tmp.write_all(current_text.as_bytes())
+    let tmp = NamedTempFile::new().map_err(|e| e.to_string())?;
+    let content = run_editor(tmp, current_text)?;
+    let trimmed = content.trim().to_string();
+    if trimmed.is_empty() {
+        return Ok(None);
+    }
+
+    Ok(Some(trimmed))
+}
+
+// Edit a whole file's text, returned as saved (it may be empty). The temp
+// file ends in .md so editors pick markdown highlighting.
+pub fn edit_file_in_external_editor(contents: &str) -> Result<String, String> {
+    let tmp = Builder::new()
+        .suffix(".md")
+        .tempfile()
+        .map_err(|e| e.to_string())?;
+    run_editor(tmp, contents)
+}
+
+// $VISUAL, then $EDITOR, then vim. The value is split on whitespace so a
+// command with flags like `code --wait` works; quoting isn't understood.
+fn editor_command() -> (String, Vec<String>) {
+    let value = ["VISUAL", "EDITOR"]
+        .iter()
+        .filter_map(|name| std::env::var(name).ok())
+        .find(|value| !value.trim().is_empty())
+        .unwrap_or_else(|| "vim".to_string());
+    let mut fields = value.split_whitespace().map(str::to_string);
+    let program = fields.next().unwrap_or_else(|| "vim".to_string());
+    (program, fields.collect())
+}
+
+fn run_editor(mut tmp: NamedTempFile, contents: &str) -> Result<String, String> {
+    tmp.write_all(contents.as_bytes())
         .map_err(|e| e.to_string())?;
     let path = tmp.path().to_path_buf();
 
     let _suspend = TerminalSuspend::new().map_err(|e| e.to_string())?;
 
-    let editor = std::env::var("EDITOR").unwrap_or_else(|_| "vim".to_string());
-    let status = Command::new(editor)
+    let (program, args) = editor_command();
+    let status = Command::new(program)
+        .args(args)
         .arg(&path)
         .status()
         .map_err(|e| e.to_string())?;
@@ -49,11 +83,5 @@ pub fn edit_in_external_editor(current_text: &str) -> Result<Option<String>, Str
         return Err("Editor error".to_string());
     }
 
-    let content = fs::read_to_string(&path).map_err(|e| e.to_string())?;
-    let trimmed = content.trim().to_string();
-    if trimmed.is_empty() {
-        return Ok(None);
-    }
-
-    Ok(Some(trimmed))
+    fs::read_to_string(&path).map_err(|e| e.to_string())
 }
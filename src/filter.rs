@@ -0,0 +1,123 @@
+use crate::keys::Key;
+use crate::metadata::tags;
+use crate::model::{App, Filter, LineItem, Mode, Overlay, Task};
+
+impl Filter {
+    // Footer label for the active filter; None when every task shows.
+    pub fn label(self) -> Option<&'static str> {
+        match self {
+            Filter::All => None,
+            Filter::Open => Some("open only"),
+            Filter::Done => Some("done only"),
+        }
+    }
+
+    pub fn allows(self, task: &Task) -> bool {
+        match self {
+            Filter::All => true,
+            Filter::Open => !task.completed,
+            Filter::Done => task.completed,
+        }
+    }
+}
+
+impl App {
+    // `f`: cycle the list between all, open-only and done-only tasks. Only
+    // the view changes; saves still write every line.
+    pub fn cycle_filter(&mut self) {
+        self.filter = match self.filter {
+            Filter::All => Filter::Open,
+            Filter::Open => Filter::Done,
+            Filter::Done => Filter::All,
+        };
+        self.clear_selection();
+        self.clamp_cursor_to_visible();
+        self.set_status(match self.filter.label() {
+            Some(label) => format!("Filter: {}", label),
+            None => "Filter off".to_string(),
+        });
+    }
+
+    // Drop tasks the active filters hide; other lines are kept.
+    pub(crate) fn apply_filter(&self, mut indices: Vec<usize>) -> Vec<usize> {
+        if self.filtering() {
+            indices.retain(|&i| match &self.lines[i] {
+                LineItem::Task(task) => self.filter_allows(task),
+                _ => true,
+            });
+        }
+        indices
+    }
+
+    // Whether the completion filter and the tag filter both show `task`.
+    pub(crate) fn filter_allows(&self, task: &Task) -> bool {
+        self.filter.allows(task)
+            && self
+                .tag_filter
+                .as_ref()
+                .is_none_or(|tag| tags(&task.text).contains(tag))
+    }
+
+    pub(crate) fn filtering(&self) -> bool {
+        self.filter != Filter::All || self.tag_filter.is_some()
+    }
+
+    // `%`: the tag progress overlay, which doubles as the tag filter picker.
+    pub fn open_tag_picker(&mut self) {
+        let counts = self.tag_counts();
+        self.tag_cursor = self
+            .tag_filter
+            .as_ref()
+            .and_then(|tag| counts.iter().position(|(name, _, _)| name == tag))
+            .unwrap_or(0);
+        self.open_overlay(Overlay::Tags);
+        self.scroll_to_tag_cursor();
+    }
+
+    pub(crate) fn handle_tag_picker_key(&mut self, key: Key) {
+        let counts = self.tag_counts();
+        let last = counts.len().saturating_sub(1);
+        match key {
+            Key::Esc | Key::Char('q') | Key::Ctrl('c') => self.mode = Mode::Normal,
+            Key::Char('j') | Key::Down => self.tag_cursor = (self.tag_cursor + 1).min(last),
+            Key::Char('k') | Key::Up => self.tag_cursor = self.tag_cursor.saturating_sub(1),
+            Key::Char('g') | Key::Home => self.tag_cursor = 0,
+            Key::Char('G') | Key::End => self.tag_cursor = last,
+            Key::Enter => {
+                self.mode = Mode::Normal;
+                if let Some((tag, _, _)) = counts.get(self.tag_cursor) {
+                    self.set_tag_filter(Some(tag.clone()));
+                }
+                return;
+            }
+            Key::Char('x') => {
+                self.mode = Mode::Normal;
+                self.set_tag_filter(None);
+                return;
+            }
+            _ => {}
+        }
+        self.scroll_to_tag_cursor();
+    }
+
+    // Show only tasks tagged `tag`, or every task again for None. Like `f`,
+    // this only changes the view.
+    pub(crate) fn set_tag_filter(&mut self, tag: Option<String>) {
+        self.set_status(match &tag {
+            Some(tag) => format!("Showing #{}", tag),
+            None => "Tag filter off".to_string(),
+        });
+        self.tag_filter = tag;
+        self.clear_selection();
+        self.clamp_cursor_to_visible();
+    }
+
+    fn scroll_to_tag_cursor(&mut self) {
+        let height = self.overlay_body_height();
+        if self.tag_cursor < self.overlay_scroll {
+            self.overlay_scroll = self.tag_cursor;
+        } else if self.tag_cursor >= self.overlay_scroll.saturating_add(height) {
+            self.overlay_scroll = self.tag_cursor + 1 - height;
+        }
+    }
+}
@@ -0,0 +1,242 @@
+use log::debug;
+
+use crate::edit::get_indent_level;
+use crate::model::{App, LineItem};
+use crate::state::{load_folds, save_folds, SavedFold};
+
+impl App {
+    // Collapse every section so only headers show; toggling again expands all.
+    pub fn toggle_outline(&mut self) {
+        self.outline = !self.outline;
+        let outline = self.outline;
+        for line in &mut self.lines {
+            if let LineItem::Section { collapsed, .. } = line {
+                *collapsed = outline;
+            }
+        }
+        self.clear_selection();
+        self.clamp_cursor_to_visible();
+        if outline {
+            self.set_status("Outline");
+        } else {
+            self.set_status("Outline off");
+        }
+    }
+
+    // Expand the collapsed section under the cursor. Returns false if the
+    // cursor isn't on a collapsed header.
+    pub fn expand_current_section(&mut self) -> bool {
+        match self.lines.get_mut(self.cursor) {
+            Some(LineItem::Section { collapsed, .. }) if *collapsed => {
+                *collapsed = false;
+                self.outline = false;
+                self.set_status("Expanded");
+                true
+            }
+            _ => false,
+        }
+    }
+
+    // Make sure a line inserted at `index` won't land inside a collapsed section.
+    pub(crate) fn expand_section_containing(&mut self, index: usize) {
+        if let Some(header) = self.enclosing_header(index) {
+            if let LineItem::Section { collapsed, .. } = &mut self.lines[header] {
+                *collapsed = false;
+            }
+        }
+    }
+
+    // Tasks and headers left after folding; only sections and file headers
+    // remain for a collapsed section or in outline mode.
+    pub(crate) fn unfolded_indices(&self) -> Vec<usize> {
+        let mut hidden = self.outline;
+        // Indent level of the folded task whose subtasks are being skipped.
+        let mut folded_level: Option<usize> = None;
+        let mut indices = Vec::with_capacity(self.lines.len());
+        for (idx, line) in self.lines.iter().enumerate() {
+            match line {
+                LineItem::Section { collapsed, .. } => {
+                    hidden = *collapsed;
+                    folded_level = None;
+                    indices.push(idx);
+                }
+                LineItem::File { .. } => {
+                    hidden = self.outline;
+                    folded_level = None;
+                    indices.push(idx);
+                }
+                LineItem::Rule | LineItem::Raw { .. } => {
+                    folded_level = None;
+                    if !hidden {
+                        indices.push(idx);
+                    }
+                }
+                LineItem::Comment { .. } => {
+                    if self.show_comments && !hidden && folded_level.is_none() {
+                        indices.push(idx);
+                    }
+                }
+                LineItem::Task(task) => {
+                    let level = get_indent_level(&task.indent, &self.indent_unit);
+                    if folded_level.is_some_and(|folded| level > folded) {
+                        continue;
+                    }
+                    folded_level = task.folded.then_some(level);
+                    if !hidden {
+                        indices.push(idx);
+                    }
+                }
+            }
+        }
+        indices
+    }
+
+    // `za`/Tab: collapse or expand the section under the cursor, or fold the
+    // current task's subtasks.
+    pub fn toggle_fold(&mut self) {
+        let Some(LineItem::Section { collapsed, .. }) = self.lines.get_mut(self.cursor) else {
+            self.toggle_task_fold();
+            return;
+        };
+        *collapsed = !*collapsed;
+        let collapsed = *collapsed;
+        self.set_status(if collapsed { "Collapsed" } else { "Expanded" });
+        if !collapsed {
+            self.outline = false;
+        }
+        self.clear_selection();
+    }
+
+    // Hide or show the subtasks nested under the current task.
+    pub fn toggle_task_fold(&mut self) {
+        if self.task_block_end(self.cursor) <= self.cursor + 1 {
+            self.set_status("No subtasks to fold");
+            return;
+        }
+        let Some(LineItem::Task(task)) = self.lines.get_mut(self.cursor) else {
+            return;
+        };
+        task.folded = !task.folded;
+        let folded = task.folded;
+        self.set_status(if folded { "Folded" } else { "Unfolded" });
+        self.clear_selection();
+    }
+
+    // Where `o` and `p` insert below the cursor: past a folded task's hidden
+    // subtasks so the new line doesn't land inside them.
+    pub(crate) fn insert_below_index(&self) -> usize {
+        match self.lines.get(self.cursor) {
+            Some(LineItem::Task(task)) if task.folded => self.task_block_end(self.cursor),
+            _ => self.cursor + 1,
+        }
+    }
+
+    // Completion of the tasks between a section header and the next header
+    // (or file marker).
+    pub fn section_progress(&self, index: usize) -> (usize, usize) {
+        let mut done = 0;
+        let mut total = 0;
+        for line in self.lines.iter().skip(index + 1) {
+            match line {
+                LineItem::Task(task) => {
+                    total += 1;
+                    if task.completed {
+                        done += 1;
+                    }
+                }
+                LineItem::Rule | LineItem::Comment { .. } | LineItem::Raw { .. } => {}
+                LineItem::Section { .. } | LineItem::File { .. } => break,
+            }
+        }
+        (done, total)
+    }
+
+    // Remember which sections and tasks are folded so reopening the file
+    // restores them.
+    pub fn save_fold_state(&self) {
+        let folds: Vec<SavedFold> = self
+            .lines
+            .iter()
+            .enumerate()
+            .filter_map(|(index, line)| match fold_text(line) {
+                Some((task, text, true)) => Some(SavedFold {
+                    task,
+                    index,
+                    text: text.to_string(),
+                }),
+                _ => None,
+            })
+            .collect();
+        if let Err(err) = save_folds(&self.file_path, &folds) {
+            debug!("failed to save fold state: {}", err);
+        }
+    }
+
+    pub(crate) fn restore_fold_state(&mut self) {
+        apply_saved_folds(&mut self.lines, &load_folds(&self.file_path));
+    }
+
+    // Section or file header that `index` sits under, if any.
+    pub(crate) fn enclosing_header(&self, index: usize) -> Option<usize> {
+        if self.lines.is_empty() {
+            return None;
+        }
+        (0..=index.min(self.lines.len() - 1))
+            .rev()
+            .find(|&i| self.lines[i].is_section() || self.lines[i].is_file())
+    }
+}
+
+// Whether a foldable line is a task, the text it's matched by, and whether
+// it's currently folded. None for lines that can't fold.
+fn fold_text(line: &LineItem) -> Option<(bool, &str, bool)> {
+    match line {
+        LineItem::Section { title, collapsed } => Some((false, title, *collapsed)),
+        LineItem::Task(task) => Some((true, &task.text, task.folded)),
+        LineItem::File { .. }
+        | LineItem::Rule
+        | LineItem::Comment { .. }
+        | LineItem::Raw { .. } => None,
+    }
+}
+
+fn set_folded(line: &mut LineItem, folded: bool) {
+    match line {
+        LineItem::Section { collapsed, .. } => *collapsed = folded,
+        LineItem::Task(task) => task.folded = folded,
+        LineItem::File { .. }
+        | LineItem::Rule
+        | LineItem::Comment { .. }
+        | LineItem::Raw { .. } => {}
+    }
+}
+
+// Keep sections and tasks folded across a reload by matching them up by text.
+pub fn carry_over_folds(old: &[LineItem], new: &mut [LineItem]) {
+    for line in new.iter_mut() {
+        let Some((task, text, _)) = fold_text(line) else {
+            continue;
+        };
+        let folded = old
+            .iter()
+            .any(|prev| fold_text(prev) == Some((task, text, true)));
+        set_folded(line, folded);
+    }
+}
+
+// Fold the lines named in `saved`. Text is matched first; when it appears
+// more than once, the line closest to the saved index wins. Entries whose
+// line no longer exists are ignored.
+pub fn apply_saved_folds(lines: &mut [LineItem], saved: &[SavedFold]) {
+    for fold in saved {
+        let best = lines
+            .iter()
+            .enumerate()
+            .filter(|(_, line)| fold_text(line) == Some((fold.task, fold.text.as_str(), false)))
+            .min_by_key(|(idx, _)| idx.abs_diff(fold.index))
+            .map(|(idx, _)| idx);
+        if let Some(idx) = best {
+            set_folded(&mut lines[idx], true);
+        }
+    }
+}
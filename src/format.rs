@@ -0,0 +1,159 @@
+use crate::config::FormatConfig;
+use crate::edit::{get_indent_level, indent_for, ordered_marker, renumber_run};
+use crate::model::{App, LineItem};
+
+// Counts of what a format pass changed, used for the status summary.
+#[derive(Debug, Default, Clone, Copy, PartialEq, Eq)]
+pub struct FormatSummary {
+    pub bullets: usize,
+    pub indents: usize,
+    pub trailing: usize,
+    pub numbers: usize,
+}
+
+impl FormatSummary {
+    pub fn is_empty(&self) -> bool {
+        self.bullets == 0 && self.indents == 0 && self.trailing == 0 && self.numbers == 0
+    }
+
+    pub fn describe(&self) -> String {
+        let mut parts = Vec::new();
+        if self.bullets > 0 {
+            parts.push(format!("{} bullets", self.bullets));
+        }
+        if self.indents > 0 {
+            parts.push(format!("{} indents", self.indents));
+        }
+        if self.trailing > 0 {
+            parts.push(format!("{} trailing spaces", self.trailing));
+        }
+        if self.numbers > 0 {
+            parts.push(format!("{} list numbers", self.numbers));
+        }
+        parts.join(", ")
+    }
+}
+
+// Normalize every line in place according to the enabled format options,
+// snapping indents to whole steps of `unit`.
+pub fn format_lines(lines: &mut [LineItem], config: &FormatConfig, unit: &str) -> FormatSummary {
+    let mut summary = FormatSummary::default();
+    for line in lines.iter_mut() {
+        match line {
+            LineItem::Section { title, .. } => {
+                if config.trailing_whitespace && trim_trailing(title) {
+                    summary.trailing += 1;
+                }
+            }
+            LineItem::Task(task) => {
+                // Ordered markers (`1.`) carry numbering, so they're left alone.
+                if config.bullets
+                    && task.bullet != config.bullet
+                    && ordered_marker(&task.bullet).is_none()
+                {
+                    task.bullet = config.bullet.clone();
+                    summary.bullets += 1;
+                }
+                if config.indent {
+                    let normalized = indent_for(get_indent_level(&task.indent, unit), unit);
+                    // An off-grid indent kept from the file counts too.
+                    if task.indent != normalized || task.file_indent.is_some() {
+                        task.indent = normalized;
+                        task.file_indent = None;
+                        summary.indents += 1;
+                    }
+                }
+                if config.trailing_whitespace && trim_trailing(&mut task.text) {
+                    summary.trailing += 1;
+                }
+            }
+            LineItem::File { .. }
+            | LineItem::Rule
+            | LineItem::Comment { .. }
+            | LineItem::Raw { .. } => {}
+        }
+    }
+    // After the indent pass, so runs are found by their final nesting.
+    if config.numbering {
+        for index in 0..lines.len() {
+            summary.numbers += renumber_run(lines, index, unit);
+        }
+    }
+    summary
+}
+
+fn trim_trailing(text: &mut String) -> bool {
+    let trimmed_len = text.trim_end().len();
+    if trimmed_len == text.len() {
+        return false;
+    }
+    text.truncate(trimmed_len);
+    true
+}
+
+impl App {
+    pub fn format_document(&mut self) {
+        let mut formatted = self.lines.clone();
+        let summary = format_lines(&mut formatted, &self.config.format, &self.indent_unit);
+        if summary.is_empty() {
+            self.set_status("Already formatted");
+            return;
+        }
+
+        self.apply_bulk(formatted, &format!("Formatted: {}", summary.describe()));
+    }
+
+    // `B`: only the bullet part of `=`, rewriting every task's `-`/`*`/`+`
+    // to `[format] bullet` even when `[format] bullets` is off.
+    pub fn normalize_bullets(&mut self) {
+        let config = FormatConfig {
+            bullets: true,
+            indent: false,
+            trailing_whitespace: false,
+            numbering: false,
+            ..self.config.format.clone()
+        };
+        let mut formatted = self.lines.clone();
+        let summary = format_lines(&mut formatted, &config, &self.indent_unit);
+        if summary.bullets == 0 {
+            self.set_status(format!("Every bullet is already {}", config.bullet));
+            return;
+        }
+
+        let noun = if summary.bullets == 1 {
+            "bullet"
+        } else {
+            "bullets"
+        };
+        self.apply_bulk(formatted, &format!("Changed {} {}", summary.bullets, noun));
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::config::Config;
+    use crate::io::{parse_lines, serialize_lines, LineEnding};
+
+    #[test]
+    fn format_renumbers_ordered_runs() {
+        let mut lines =
+            parse_lines("1. [ ] a\n1. [ ] b\n    - [ ] sub\n7. [ ] c\n\n3) [ ] d\n3) [ ] e\n");
+        let summary = format_lines(&mut lines, &Config::default().format, "    ");
+        assert_eq!(summary.numbers, 3);
+        assert_eq!(
+            serialize_lines(&lines, LineEnding::Lf),
+            "1. [ ] a\n2. [ ] b\n    - [ ] sub\n3. [ ] c\n\n3) [ ] d\n4) [ ] e\n"
+        );
+        assert!(summary.describe().contains("3 list numbers"));
+    }
+
+    #[test]
+    fn format_leaves_numbers_alone_when_numbering_is_off() {
+        let mut config = Config::default().format;
+        config.numbering = false;
+        let mut lines = parse_lines("1. [ ] a\n1. [ ] b\n");
+        let summary = format_lines(&mut lines, &config, "    ");
+        assert!(summary.is_empty());
+    }
+}
@@ -0,0 +1,219 @@
+use crate::config::KEY_ACTIONS;
+use crate::keys::Key;
+use crate::model::{App, Mode};
+
+// Every normal-mode binding, grouped for the `?` overlay. `{name}` stands for
+// the key bound to that KEY_ACTIONS action, so rows follow `[keys]` remaps;
+// every action needs a row. Keep this in step with handle_normal_key when
+// adding or changing keys.
+const HELP: &[(&str, &[(&str, &str)])] = &[
+    (
+        "Navigation",
+        &[
+            ("{down}/{up}, arrows", "move down/up (5{down}: five lines)"),
+            (
+                "{first}{first}/{last}, 10{last}",
+                "first/last line, line 10",
+            ),
+            ("{command}42", "go to line 42"),
+            ("[/] or {/}", "previous/next section header"),
+            ("Ctrl+n/Ctrl+p", "next/previous incomplete task"),
+            ("``", "back to the last edited task"),
+            ("{search}", "search; Enter jumps to the first match"),
+            ("{next_match}/{prev_match}", "next/previous search match"),
+            ("{filter}", "show all, open or done tasks"),
+        ],
+    ),
+    (
+        "Tasks",
+        &[
+            ("{toggle}/Enter", "toggle completion"),
+            ("{triage}", "toggle and move to the next task"),
+            ("{toggle_subtasks}", "toggle every subtask"),
+            ("{toggle_and_file}", "toggle and file under To Do/Done"),
+            ("{due_date}", "pick a due date"),
+            ("Ctrl+d", "relative/absolute due dates"),
+            (
+                "{color} + r/g/y/b/m/c, {color}x",
+                "color the checkbox, or clear it",
+            ),
+            ("{invert_all}", "invert every task"),
+            ("{reset_all}", "reset every task"),
+        ],
+    ),
+    (
+        "Editing",
+        &[
+            ("{edit_inline}", "edit inline"),
+            ("{edit_external}", "edit in $EDITOR"),
+            ("{edit_file}", "edit the whole file in $EDITOR"),
+            ("{insert_below}/{insert_above}", "new task below/above"),
+            ("{insert_section}", "new section"),
+            ("{daily}", "today's daily log"),
+            ("{separator}", "insert a --- separator"),
+            (">>/<<", "indent/outdent"),
+            (">1-9", "move to numbered section"),
+            (
+                "{move_down}/{move_up}, Ctrl+j/Ctrl+k",
+                "move the line down/up",
+            ),
+            ("{first}J", "join the next task onto this one"),
+            ("{delete}{delete}, 3{delete}{delete}", "delete lines"),
+            (
+                "{yank}{yank}, {paste}/{paste_above}",
+                "yank, paste below/above",
+            ),
+            ("{duplicate}", "duplicate the line or selection below"),
+            ("{yank}c/{yank}m/{yank}r", "copy text/markdown/link"),
+            ("{undo}, Ctrl+r", "undo, redo"),
+            ("{format}", "format the file"),
+            ("{bullets}", "use one bullet style for every task"),
+            ("{sort_priority}", "sort the section by priority"),
+            ("{sort_done}", "sort the section's open tasks first"),
+        ],
+    ),
+    (
+        "Selection",
+        &[
+            ("{visual}", "visual line selection"),
+            ("{mark}", "mark/unmark the line"),
+            ("{delete}/x", "cut the selection"),
+            ("Esc, Ctrl+g", "clear selection / reset"),
+        ],
+    ),
+    (
+        "View",
+        &[
+            ("za/Tab", "fold the section or task"),
+            ("Shift+Tab", "outline mode"),
+            ("{split_view}", "split open/done columns"),
+            ("{focus}", "focus the task"),
+            ("{tags}", "progress by tag; Enter filters to one"),
+            ("{comment_out}, {uncomment}", "comment out, restore"),
+            ("{show_comments}", "show/hide comments"),
+            ("Ctrl+t", "styled/plain task text"),
+        ],
+    ),
+    (
+        "Files",
+        &[
+            (
+                "{first}t/{first}T, 2{first}t",
+                "next/previous file tab, tab 2 ({first}{first}: first line)",
+            ),
+            ("{inbox}", "toggle the inbox"),
+            (
+                "{move_to_other_buffer}",
+                "move the line to the other buffer",
+            ),
+            ("{reload}", "reload"),
+            ("{force_reload}", "force reload, dropping history"),
+            ("{quit}", "quit"),
+            ("{help}", "this help"),
+        ],
+    ),
+];
+
+impl App {
+    // Rows of the help overlay, with each action's key as currently bound and
+    // the keys padded to one column.
+    pub(crate) fn help_lines(&self) -> Vec<String> {
+        let rows: Vec<Vec<(String, String)>> = HELP
+            .iter()
+            .map(|(_, entries)| {
+                entries
+                    .iter()
+                    .map(|(keys, desc)| (self.resolve_keys(keys), self.resolve_keys(desc)))
+                    .collect()
+            })
+            .collect();
+        let width = rows
+            .iter()
+            .flatten()
+            .map(|(keys, _)| keys.chars().count())
+            .max()
+            .unwrap_or(0);
+        let mut lines = Vec::new();
+        for ((group, _), entries) in HELP.iter().zip(&rows) {
+            if !lines.is_empty() {
+                lines.push(String::new());
+            }
+            lines.push(format!("\x1b[1m{}\x1b[0m", group));
+            for (keys, desc) in entries {
+                lines.push(format!("  {:<width$}  {}", keys, desc, width = width));
+            }
+        }
+        lines
+    }
+
+    // Replace each `{action}` in a HELP row with the key it's bound to.
+    fn resolve_keys(&self, text: &str) -> String {
+        let mut out = String::new();
+        let mut rest = text;
+        while let Some(open) = rest.find('{') {
+            out.push_str(&rest[..open]);
+            let after = &rest[open + 1..];
+            let action = after.find('}').and_then(|close| {
+                let name = &after[..close];
+                KEY_ACTIONS
+                    .iter()
+                    .find(|(action, _)| *action == name)
+                    .map(|&(_, builtin)| (close, builtin))
+            });
+            match action {
+                Some((close, builtin)) => {
+                    out.push_str(&match self.config.keys.key_for(builtin) {
+                        Some(' ') => "Space".to_string(),
+                        Some(key) => key.to_string(),
+                        None => "(unbound)".to_string(),
+                    });
+                    rest = &after[close + 1..];
+                }
+                // Not a placeholder, such as the `{` in `{/}`.
+                None => {
+                    out.push('{');
+                    rest = after;
+                }
+            }
+        }
+        out.push_str(rest);
+        out
+    }
+
+    // j/k/g/G scroll a help page taller than the window; any other key
+    // closes it.
+    pub(crate) fn handle_help_key(&mut self, key: Key, max_scroll: usize) {
+        match key {
+            Key::Char('j') | Key::Down if max_scroll > 0 => {
+                self.overlay_scroll = (self.overlay_scroll + 1).min(max_scroll)
+            }
+            Key::Char('k') | Key::Up if max_scroll > 0 => {
+                self.overlay_scroll = self.overlay_scroll.saturating_sub(1)
+            }
+            Key::Char('g') | Key::Home if max_scroll > 0 => self.overlay_scroll = 0,
+            Key::Char('G') | Key::End if max_scroll > 0 => self.overlay_scroll = max_scroll,
+            _ => self.mode = Mode::Normal,
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn every_action_has_a_help_row() {
+        let rows: Vec<&str> = HELP
+            .iter()
+            .flat_map(|(_, entries)| entries.iter().map(|(keys, _)| *keys))
+            .collect();
+        for (action, _) in KEY_ACTIONS {
+            let placeholder = format!("{{{}}}", action);
+            assert!(
+                rows.iter().any(|keys| keys.contains(&placeholder)),
+                "no help row for {}",
+                action
+            );
+        }
+    }
+}
@@ -0,0 +1,137 @@
+use std::path::PathBuf;
+
+use crate::app::default_task_template;
+use crate::io::{detect_indent_unit, line_ending_of, load_lines, save_lines};
+use crate::model::{App, BufferState, UndoState, MAX_UNDO_HISTORY};
+
+const INBOX_FILE: &str = "inbox.md";
+
+impl App {
+    // Swap between the main file and the inbox, loading the inbox on first use.
+    pub fn toggle_inbox(&mut self) {
+        let mut other = match self.stashed_buffer.take() {
+            Some(buffer) => buffer,
+            None => match self.load_inbox() {
+                Ok(buffer) => buffer,
+                Err(err) => {
+                    self.error = Some(err);
+                    return;
+                }
+            },
+        };
+
+        self.swap_buffer(&mut other);
+        self.stashed_buffer = Some(other);
+        self.inbox_active = !self.inbox_active;
+        self.clear_selection();
+        self.search_input.reset();
+        self.edit_template = default_task_template(&self.lines);
+        self.error = None;
+        self.set_status(format!("Switched to {}", self.buffer_name()));
+    }
+
+    // Move the current line to the end of the other buffer (inbox <-> main file).
+    pub fn move_to_other_buffer(&mut self) {
+        if self.lines.is_empty() || self.lines[self.cursor].is_file() {
+            self.set_status("Nothing to move");
+            return;
+        }
+        if self.stashed_buffer.is_none() {
+            match self.load_inbox() {
+                Ok(buffer) => self.stashed_buffer = Some(buffer),
+                Err(err) => {
+                    self.error = Some(err);
+                    return;
+                }
+            }
+        }
+        let Some(other) = self.stashed_buffer.as_mut() else {
+            return;
+        };
+
+        let mut other_lines = other.lines.clone();
+        other_lines.push(self.lines[self.cursor].clone());
+        match save_lines(&other.file_path, &other_lines, other.line_ending) {
+            Ok(mod_time) => {
+                let previous = std::mem::replace(&mut other.lines, other_lines);
+                other.undo_stack.push(UndoState {
+                    lines: previous,
+                    cursor: other.cursor,
+                });
+                if other.undo_stack.len() > MAX_UNDO_HISTORY {
+                    other.undo_stack.remove(0);
+                }
+                other.redo_stack.clear();
+                other.last_modified = mod_time;
+            }
+            Err(err) => {
+                self.error = Some(err.to_string());
+                return;
+            }
+        }
+        let destination = file_name(&other.file_path);
+
+        self.save_undo_state();
+        self.clear_selection();
+        self.lines.remove(self.cursor);
+        self.clamp_cursor_to_visible();
+        self.save_and_set_status(&format!("Moved to {}", destination));
+    }
+
+    fn load_inbox(&self) -> Result<BufferState, String> {
+        let path = self.inbox_path();
+        let (lines, mod_time) = load_lines(&path).map_err(|e| e.to_string())?;
+        let line_ending = line_ending_of(&path);
+        let indent_unit =
+            detect_indent_unit(&lines).unwrap_or_else(|| self.config.format.indent_unit());
+        Ok(BufferState {
+            file_path: path,
+            lines,
+            cursor: 0,
+            scroll_offset: 0,
+            last_modified: mod_time,
+            undo_stack: Vec::new(),
+            redo_stack: Vec::new(),
+            dirty: false,
+            indent_unit,
+            line_ending,
+        })
+    }
+
+    fn inbox_path(&self) -> PathBuf {
+        if let Some(path) = &self.config.inbox.path {
+            return path.clone();
+        }
+        if self.file_path.is_dir() {
+            return self.file_path.join(INBOX_FILE);
+        }
+        self.file_path
+            .parent()
+            .map(|dir| dir.join(INBOX_FILE))
+            .unwrap_or_else(|| PathBuf::from(INBOX_FILE))
+    }
+
+    pub(crate) fn swap_buffer(&mut self, other: &mut BufferState) {
+        std::mem::swap(&mut self.file_path, &mut other.file_path);
+        std::mem::swap(&mut self.lines, &mut other.lines);
+        std::mem::swap(&mut self.cursor, &mut other.cursor);
+        std::mem::swap(&mut self.scroll_offset, &mut other.scroll_offset);
+        std::mem::swap(&mut self.last_modified, &mut other.last_modified);
+        std::mem::swap(&mut self.undo_stack, &mut other.undo_stack);
+        std::mem::swap(&mut self.redo_stack, &mut other.redo_stack);
+        std::mem::swap(&mut self.dirty, &mut other.dirty);
+        std::mem::swap(&mut self.indent_unit, &mut other.indent_unit);
+        std::mem::swap(&mut self.line_ending, &mut other.line_ending);
+    }
+
+    fn buffer_name(&self) -> String {
+        file_name(&self.file_path)
+    }
+}
+
+fn file_name(path: &std::path::Path) -> String {
+    path.file_name()
+        .and_then(|s| s.to_str())
+        .unwrap_or(INBOX_FILE)
+        .to_string()
+}
@@ -1,18 +1,72 @@
+use std::collections::HashSet;
 use std::fs;
-use std::path::Path;
+use std::io::Write;
+use std::path::{Path, PathBuf};
 use std::time::SystemTime;
 
 use once_cell::sync::Lazy;
 use regex::Regex;
+use tempfile::NamedTempFile;
 
-use crate::model::{LineItem, Task};
+use crate::edit::{get_indent_level, indent_for, indent_width};
+use crate::model::{LineItem, Task, MAX_INDENT_LEVEL};
 
-static CHECKBOX_RE: Lazy<Regex> =
-    Lazy::new(|| Regex::new(r"^(\s*)([-*])\s+\[([ xX])\]\s*(.*)$").expect("valid checkbox regex"));
+static CHECKBOX_RE: Lazy<Regex> = Lazy::new(|| {
+    Regex::new(r"^(\s*)([-*+]|\d{1,9}[.)])\s+\[([ xX])\]\s*(.*)$").expect("valid checkbox regex")
+});
+
+static RULE_RE: Lazy<Regex> =
+    Lazy::new(|| Regex::new(r"^\s{0,3}(?:-{3,}|\*{3,}|_{3,})\s*$").expect("valid rule regex"));
+
+// Lines that look like a checkbox task but don't parse as one, such as
+// `- [] text`, `-[ ] text`, `- [ x] text` or `- [y] text`. One-letter
+// links (`- [a](url)`, `[a][1]`) and reference definitions (`[1]: url`)
+// are not checkboxes.
+static NEAR_CHECKBOX_RE: Lazy<Regex> = Lazy::new(|| {
+    Regex::new(r"^\s*[-*+]?\s*\[ {0,2}\S? {0,2}\](?:[^(\[:]|$)").expect("valid near-checkbox regex")
+});
 
 static SECTION_RE: Lazy<Regex> =
     Lazy::new(|| Regex::new(r"^##\s+(.*)$").expect("valid section regex"));
 
+// Line terminator a file is written with, kept as found on load.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum LineEnding {
+    Lf,
+    Crlf,
+}
+
+impl LineEnding {
+    // The platform's convention, used for new and empty files.
+    pub fn native() -> Self {
+        if cfg!(windows) {
+            LineEnding::Crlf
+        } else {
+            LineEnding::Lf
+        }
+    }
+
+    // The ending most lines in `data` use; LF on a tie.
+    pub fn detect(data: &str) -> Self {
+        let total = data.matches('\n').count();
+        let crlf = data.matches("\r\n").count();
+        if total == 0 {
+            LineEnding::native()
+        } else if crlf * 2 > total {
+            LineEnding::Crlf
+        } else {
+            LineEnding::Lf
+        }
+    }
+
+    fn as_str(self) -> &'static str {
+        match self {
+            LineEnding::Lf => "\n",
+            LineEnding::Crlf => "\r\n",
+        }
+    }
+}
+
 pub fn load_lines(path: &Path) -> Result<(Vec<LineItem>, SystemTime), std::io::Error> {
     let data = match fs::read_to_string(path) {
         Ok(contents) => contents,
@@ -22,52 +76,499 @@ pub fn load_lines(path: &Path) -> Result<(Vec<LineItem>, SystemTime), std::io::E
         Err(err) => return Err(err),
     };
 
-    // Destructive parsing: only section headers and checkbox tasks are retained.
+    let items = parse_lines(&data);
+    let mod_time = fs::metadata(path)
+        .and_then(|meta| meta.modified())
+        .unwrap_or(SystemTime::UNIX_EPOCH);
+
+    Ok((items, mod_time))
+}
+
+// Sections, tasks, rules and comments get their own line kinds; every other
+// line (including blank ones) is kept as raw text so saving is lossless.
+pub fn parse_lines(data: &str) -> Vec<LineItem> {
     let normalized = data.replace('\r', "");
     let mut items = Vec::new();
-    for line in normalized.split('\n') {
-        if line.trim().is_empty() {
+    let mut in_comment = false;
+    for line in normalized.lines() {
+        if is_comment_line(line, &mut in_comment) {
+            items.push(LineItem::Comment {
+                text: line.to_string(),
+            });
             continue;
         }
         if let Some(caps) = SECTION_RE.captures(line) {
             let title = caps.get(1).map(|m| m.as_str()).unwrap_or("").to_string();
-            items.push(LineItem::Section { title });
+            items.push(LineItem::Section {
+                title,
+                collapsed: false,
+            });
+            continue;
+        }
+        if RULE_RE.is_match(line) {
+            items.push(LineItem::Rule);
             continue;
         }
-        if let Some(caps) = CHECKBOX_RE.captures(line) {
-            let indent = caps.get(1).map(|m| m.as_str()).unwrap_or("").to_string();
-            let bullet = caps.get(2).map(|m| m.as_str()).unwrap_or("-").to_string();
-            let mark = caps.get(3).map(|m| m.as_str()).unwrap_or(" ");
-            let text = caps.get(4).map(|m| m.as_str()).unwrap_or("").to_string();
-            items.push(LineItem::Task(Task {
-                indent,
-                bullet,
-                completed: mark.eq_ignore_ascii_case("x"),
-                text,
-            }));
+        match parse_task(line) {
+            Some(task) => items.push(LineItem::Task(task)),
+            None => items.push(LineItem::Raw {
+                text: line.to_string(),
+            }),
         }
     }
 
-    let mod_time = fs::metadata(path)
-        .and_then(|meta| meta.modified())
-        .unwrap_or(SystemTime::UNIX_EPOCH);
+    if let Some(unit) = detect_indent_unit(&items) {
+        normalize_nesting(&mut items, &unit);
+    }
+    items
+}
 
-    Ok((items, mod_time))
+// The step the file nests tasks by: a tab, or the most common increase in
+// leading spaces from one task to the next (the smaller on a tie). None when
+// no task is nested.
+pub fn detect_indent_unit(lines: &[LineItem]) -> Option<String> {
+    let indents: Vec<&str> = lines
+        .iter()
+        .filter_map(|line| match line {
+            LineItem::Task(task) => Some(task.indent.as_str()),
+            _ => None,
+        })
+        .collect();
+    if indents.iter().any(|indent| indent.starts_with('\t')) {
+        return Some("\t".to_string());
+    }
+
+    let mut steps: Vec<(usize, usize)> = Vec::new();
+    for pair in indents.windows(2) {
+        let (prev, next) = (pair[0].len(), pair[1].len());
+        if next <= prev {
+            continue;
+        }
+        let step = next - prev;
+        match steps.iter_mut().find(|(width, _)| *width == step) {
+            Some((_, count)) => *count += 1,
+            None => steps.push((step, 1)),
+        }
+    }
+    if steps.is_empty() {
+        // Only deeper tasks with no parent above them; use the shallowest.
+        let shallowest = indents.iter().map(|i| i.len()).filter(|&w| w > 0).min()?;
+        return Some(" ".repeat(shallowest));
+    }
+    steps
+        .into_iter()
+        .max_by(|a, b| a.1.cmp(&b.1).then_with(|| b.0.cmp(&a.0)))
+        .map(|(width, _)| " ".repeat(width))
+}
+
+// Parse a `- [ ] text` checkbox line. `*`, `+` and ordered markers such as
+// `1.` or `2)` are accepted as bullets too.
+pub fn parse_task(line: &str) -> Option<Task> {
+    let caps = CHECKBOX_RE.captures(line)?;
+    let indent = caps.get(1).map(|m| m.as_str()).unwrap_or("").to_string();
+    let bullet = caps.get(2).map(|m| m.as_str()).unwrap_or("-").to_string();
+    let mark = caps.get(3).map(|m| m.as_str()).unwrap_or(" ");
+    let text = caps.get(4).map(|m| m.as_str()).unwrap_or("").to_string();
+    Some(Task {
+        indent,
+        bullet,
+        completed: mark.eq_ignore_ascii_case("x"),
+        mark: if mark == "X" { 'X' } else { 'x' },
+        text,
+        folded: false,
+        file_indent: None,
+    })
+}
+
+// Whether `line` is part of an HTML comment, tracking comments that span
+// several lines in `in_comment`.
+fn is_comment_line(line: &str, in_comment: &mut bool) -> bool {
+    let opens = line.trim_start().starts_with("<!--");
+    if !*in_comment && !opens {
+        return false;
+    }
+    let body = if opens { &line.trim_start()[4..] } else { line };
+    *in_comment = !body.contains("-->");
+    true
+}
+
+// Lists written outside the app may mix indent widths. When any indent is
+// off the file's `unit` grid, infer each task's depth from the tasks above
+// it and move indents that get_indent_level would misread onto the grid, so
+// nesting matches in-app edits. The indent as written is kept in
+// `file_indent` and saved back unless the task is reindented.
+fn normalize_nesting(items: &mut [LineItem], unit: &str) {
+    let step = indent_width(unit);
+    let off_grid = items.iter().any(|item| match item {
+        LineItem::Task(task) => !indent_width(&task.indent).is_multiple_of(step),
+        _ => false,
+    });
+    if !off_grid {
+        return;
+    }
+
+    let mut widths: Vec<usize> = Vec::new();
+    for item in items.iter_mut() {
+        let task = match item {
+            LineItem::Task(task) => task,
+            _ => {
+                widths.clear();
+                continue;
+            }
+        };
+        let width = indent_width(&task.indent);
+        while widths.last().is_some_and(|&w| w > width) {
+            widths.pop();
+        }
+        if widths.last() != Some(&width) {
+            widths.push(width);
+        }
+        let level = (widths.len() - 1).min(MAX_INDENT_LEVEL);
+        if get_indent_level(&task.indent, unit) != level {
+            let loaded = indent_for(level, unit);
+            let written = std::mem::replace(&mut task.indent, loaded.clone());
+            task.file_indent = Some((written, loaded));
+        }
+    }
+}
+
+// Load a single file, or every markdown file in a directory as a board.
+pub fn load_path(path: &Path) -> Result<(Vec<LineItem>, SystemTime), std::io::Error> {
+    if path.is_dir() {
+        load_board(path)
+    } else {
+        load_lines(path)
+    }
+}
+
+pub fn save_path(
+    path: &Path,
+    lines: &[LineItem],
+    ending: LineEnding,
+) -> Result<SystemTime, std::io::Error> {
+    if path.is_dir() {
+        save_board(lines, ending)
+    } else {
+        save_lines(path, lines, ending)
+    }
+}
+
+// Line ending of the file, or the one most board files use. Missing and
+// empty files get the platform's.
+pub fn line_ending_of(path: &Path) -> LineEnding {
+    let files = if path.is_dir() {
+        board_files(path).unwrap_or_default()
+    } else {
+        vec![path.to_path_buf()]
+    };
+    let data: String = files
+        .iter()
+        .filter_map(|file| fs::read_to_string(file).ok())
+        .collect();
+    LineEnding::detect(&data)
+}
+
+// Raw lines that look like tasks but are malformed, so the parser kept
+// them as plain text instead of loading them as tasks.
+pub fn unrecognized_lines(lines: &[LineItem]) -> Vec<String> {
+    lines
+        .iter()
+        .filter_map(|line| match line {
+            LineItem::Raw { text } if NEAR_CHECKBOX_RE.is_match(text) => Some(text.clone()),
+            _ => None,
+        })
+        .collect()
 }
 
-pub fn save_lines(path: &Path, lines: &[LineItem]) -> Result<SystemTime, std::io::Error> {
+// Whether the file (or every board file) already holds exactly what saving
+// `lines` would write, as after our own save. Lets the watcher tell those
+// apart from external edits whatever the timestamp says.
+pub fn matches_disk(path: &Path, lines: &[LineItem]) -> bool {
+    let targets = if path.is_dir() {
+        let segments = board_segments(lines);
+        if board_files(path).map_or(true, |files| files.len() != segments.len()) {
+            return false;
+        }
+        segments
+    } else {
+        vec![(path, lines.to_vec())]
+    };
+    targets.iter().all(|(path, segment)| {
+        fs::read_to_string(path)
+            .is_ok_and(|data| data == serialize_lines(segment, LineEnding::detect(&data)))
+    })
+}
+
+// Latest modification time of the file, or of any board file for a directory.
+pub fn modified_time(path: &Path) -> Result<SystemTime, std::io::Error> {
+    let mut latest = fs::metadata(path)?.modified()?;
+    if path.is_dir() {
+        for file in board_files(path)? {
+            latest = latest.max(fs::metadata(&file)?.modified()?);
+        }
+    }
+    Ok(latest)
+}
+
+pub fn board_files(dir: &Path) -> Result<Vec<PathBuf>, std::io::Error> {
+    let mut files = Vec::new();
+    for entry in fs::read_dir(dir)? {
+        let path = entry?.path();
+        if path.is_file() && path.extension().is_some_and(|ext| ext == "md") {
+            files.push(path);
+        }
+    }
+    files.sort();
+    Ok(files)
+}
+
+// Each file's lines are preceded by a `LineItem::File` marker naming it.
+fn load_board(dir: &Path) -> Result<(Vec<LineItem>, SystemTime), std::io::Error> {
+    let mut items = Vec::new();
+    for path in board_files(dir)? {
+        let (lines, _) = load_lines(&path)?;
+        items.push(LineItem::File { path });
+        items.extend(lines);
+    }
+    Ok((items, modified_time(dir)?))
+}
+
+// Split the board at its file markers and write back only the files whose
+// content actually changed. Each file keeps its own line ending; `ending`
+// is for files that have none yet.
+fn save_board(lines: &[LineItem], ending: LineEnding) -> Result<SystemTime, std::io::Error> {
+    let mut latest = SystemTime::UNIX_EPOCH;
+    for (path, segment) in board_segments(lines) {
+        let current = fs::read_to_string(path).ok();
+        let ending = current
+            .as_deref()
+            .filter(|data| data.contains('\n'))
+            .map_or(ending, LineEnding::detect);
+        let content = serialize_lines(&segment, ending);
+        if current.as_deref() != Some(content.as_str()) {
+            write_atomic(path, &content)?;
+        }
+        latest = latest.max(fs::metadata(path)?.modified()?);
+    }
+    Ok(latest)
+}
+
+// Split board lines into the file each run of lines belongs to.
+fn board_segments(lines: &[LineItem]) -> Vec<(&Path, Vec<LineItem>)> {
+    let mut segments: Vec<(&Path, Vec<LineItem>)> = Vec::new();
+    let mut orphans = Vec::new();
+    for line in lines {
+        match (line, segments.last_mut()) {
+            (LineItem::File { path }, _) => segments.push((path.as_path(), Vec::new())),
+            (_, Some((_, segment))) => segment.push(line.clone()),
+            // Lines inserted above the first marker belong to the first file.
+            (_, None) => orphans.push(line.clone()),
+        }
+    }
+    if let Some((_, first)) = segments.first_mut() {
+        first.splice(0..0, orphans);
+    }
+    segments
+}
+
+// Lines in a file that a save would drop or write back differently because
+// the parser doesn't keep them as written (`-   [ ]   x` becomes `- [ ] x`),
+// and what the parser writes in their place.
+#[derive(Debug)]
+pub struct DataLoss {
+    pub path: PathBuf,
+    pub lines: Vec<String>,
+    pub rewritten: Vec<String>,
+}
+
+// Check every file a save of `lines` to `path` would rewrite for content the
+// parser doesn't reproduce as written.
+pub fn pending_data_loss(path: &Path, lines: &[LineItem]) -> Vec<DataLoss> {
+    let targets = if path.is_dir() {
+        board_segments(lines)
+    } else {
+        vec![(path, lines.to_vec())]
+    };
+    targets
+        .into_iter()
+        .filter_map(|(path, segment)| {
+            let current = fs::read_to_string(path).ok()?;
+            if current == serialize_lines(&segment, LineEnding::detect(&current)) {
+                return None;
+            }
+            let current = current.replace('\r', "");
+            let original: HashSet<&str> = current.lines().collect();
+            let reparsed = serialize_lines(&parse_lines(&current), LineEnding::Lf);
+            let kept: HashSet<&str> = reparsed.lines().collect();
+            let lost: Vec<String> = current
+                .lines()
+                .filter(|line| !kept.contains(line))
+                .map(str::to_string)
+                .collect();
+            let rewritten = reparsed
+                .lines()
+                .filter(|line| !original.contains(line))
+                .map(str::to_string)
+                .collect();
+            (!lost.is_empty()).then(|| DataLoss {
+                path: path.to_path_buf(),
+                lines: lost,
+                rewritten,
+            })
+        })
+        .collect()
+}
+
+// Copy a file to `<name>.bak` alongside it before it is rewritten.
+pub fn write_backup(path: &Path) -> Result<PathBuf, std::io::Error> {
+    let mut name = path.file_name().unwrap_or_default().to_os_string();
+    name.push(".bak");
+    let backup = path.with_file_name(name);
+    fs::copy(path, &backup)?;
+    Ok(backup)
+}
+
+pub fn save_lines(
+    path: &Path,
+    lines: &[LineItem],
+    ending: LineEnding,
+) -> Result<SystemTime, std::io::Error> {
+    write_atomic(path, &serialize_lines(lines, ending))?;
+    let mod_time = fs::metadata(path)?.modified()?;
+    Ok(mod_time)
+}
+
+// Replace `path` with `content` by writing a temp file in the same directory
+// and renaming it over the original, so a crash mid-save can't leave a
+// truncated file. The original's permissions are kept, and symlinks are
+// followed so the link itself survives. A new file gets the usual
+// permissions for the umask rather than the temp file's private 0600.
+fn write_atomic(path: &Path, content: &str) -> Result<(), std::io::Error> {
+    let target = fs::canonicalize(path).unwrap_or_else(|_| path.to_path_buf());
+    let dir = match target.parent() {
+        Some(dir) if !dir.as_os_str().is_empty() => dir,
+        _ => Path::new("."),
+    };
+    let mut tmp = NamedTempFile::new_in(dir)?;
+    tmp.write_all(content.as_bytes())?;
+    tmp.as_file().sync_all()?;
+    let permissions = match fs::metadata(&target) {
+        Ok(meta) => meta.permissions(),
+        // Creating the file the ordinary way applies `0o666 & !umask`; the
+        // empty placeholder is replaced by the rename below.
+        Err(_) => fs::OpenOptions::new()
+            .write(true)
+            .create_new(true)
+            .open(&target)?
+            .metadata()?
+            .permissions(),
+    };
+    tmp.as_file().set_permissions(permissions)?;
+    tmp.persist(&target).map_err(|e| e.error)?;
+    Ok(())
+}
+
+pub fn serialize_lines(lines: &[LineItem], ending: LineEnding) -> String {
     let mut out = String::new();
     for (i, line) in lines.iter().enumerate() {
         out.push_str(&line.line());
         if i < lines.len() - 1 {
-            out.push('\n');
+            out.push_str(ending.as_str());
         }
     }
     // Match Go behavior: always end with a newline when non-empty.
     if !lines.is_empty() {
-        out.push('\n');
+        out.push_str(ending.as_str());
+    }
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::app::default_task_template;
+
+    fn levels(items: &[LineItem], unit: &str) -> Vec<usize> {
+        items
+            .iter()
+            .filter_map(|item| match item {
+                LineItem::Task(task) => Some(get_indent_level(&task.indent, unit)),
+                _ => None,
+            })
+            .collect()
+    }
+
+    #[test]
+    fn unrecognized_lines_reports_malformed_checkboxes() {
+        let dir = tempfile::TempDir::new().expect("temp dir");
+        let path = dir.path().join("todo.md");
+        let lines = parse_lines(
+            "## A\n- [ ] fine\n-[ ] no space\n- [] empty\n- [y] odd mark\n* [ x] padded\n- [a](https://example.com) link\n[1]: https://example.com\n[a][1]\nplain text\n",
+        );
+        assert_eq!(
+            unrecognized_lines(&lines),
+            vec![
+                "-[ ] no space",
+                "- [] empty",
+                "- [y] odd mark",
+                "* [ x] padded"
+            ]
+        );
+    }
+
+    #[test]
+    fn data_loss_reports_rewritten_lines() {
+        let dir = tempfile::TempDir::new().expect("temp dir");
+        let path = dir.path().join("todo.md");
+        let content = "##\tT\n-   [ ]   x\n- [ ] fine\n<!-- a\nb -->\n";
+        fs::write(&path, content).expect("write todo.md");
+        let losses = pending_data_loss(&path, &parse_lines(content));
+        assert_eq!(losses.len(), 1);
+        assert_eq!(losses[0].lines, vec!["##\tT", "-   [ ]   x"]);
+        assert_eq!(losses[0].rewritten, vec!["## T", "- [ ] x"]);
+    }
+
+    #[test]
+    fn parse_two_space_nesting() {
+        let items = parse_lines("* [ ] a\n  * [ ] b\n    * [ ] c\n* [ ] d\n");
+        let unit = detect_indent_unit(&items).expect("nested list");
+        assert_eq!(unit, "  ");
+        assert_eq!(levels(&items, &unit), vec![0, 1, 2, 0]);
+
+        let template = default_task_template(&items);
+        assert_eq!(template.indent, "");
+        assert_eq!(template.bullet, "*");
+    }
+
+    #[test]
+    fn parse_mixed_nesting_onto_the_grid() {
+        // A 2-space step inside a 4-space list would read as the same level.
+        let content = "- [ ] a\n    - [ ] b\n      - [ ] c\n- [ ] d\n    - [ ] e\n";
+        let items = parse_lines(content);
+        let unit = detect_indent_unit(&items).expect("nested list");
+        assert_eq!(unit, "    ");
+        assert_eq!(levels(&items, &unit), vec![0, 1, 2, 0, 1]);
+        // Saved as written until the task is reindented.
+        assert_eq!(serialize_lines(&items, LineEnding::Lf), content);
+
+        let template = default_task_template(&items);
+        assert_eq!(template.indent, "");
+        assert_eq!(template.bullet, "-");
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn write_atomic_gives_new_files_umask_permissions() {
+        use std::os::unix::fs::PermissionsExt;
+
+        let dir = tempfile::TempDir::new().expect("temp dir");
+        let plain = dir.path().join("plain.md");
+        fs::write(&plain, "").expect("write plain.md");
+        let new = dir.path().join("todo.md");
+        write_atomic(&new, "- [ ] one\n").expect("write todo.md");
+
+        let mode = |path: &Path| fs::metadata(path).unwrap().permissions().mode() & 0o777;
+        assert_eq!(mode(&new), mode(&plain));
+        assert_eq!(fs::read_to_string(&new).unwrap(), "- [ ] one\n");
     }
-    fs::write(path, out)?;
-    let mod_time = fs::metadata(path)?.modified()?;
-    Ok(mod_time)
 }
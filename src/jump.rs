@@ -0,0 +1,54 @@
+use crate::model::{App, LineItem};
+
+// Where the most recently edited or toggled task was. Lines have no stable
+// ids, so the task is found again by its text, nearest the recorded index.
+#[derive(Debug, Clone)]
+pub struct LastEdit {
+    pub index: usize,
+    pub text: String,
+}
+
+impl App {
+    // Remember the task at `index` as the target of the next backtick jump.
+    pub(crate) fn remember_edit(&mut self, index: usize) {
+        if let Some(LineItem::Task(task)) = self.lines.get(index) {
+            self.last_edit = Some(LastEdit {
+                index,
+                text: task.text.clone(),
+            });
+        }
+    }
+
+    // Move the cursor back to the last edited task, like vim's jump to the
+    // last change.
+    pub fn jump_to_last_edit(&mut self) {
+        let Some(last) = &self.last_edit else {
+            self.set_status("No edits yet");
+            return;
+        };
+        let found = self
+            .lines
+            .iter()
+            .enumerate()
+            .filter(|(_, line)| matches!(line, LineItem::Task(task) if task.text == last.text))
+            .min_by_key(|(idx, _)| idx.abs_diff(last.index))
+            .map(|(idx, _)| idx);
+        let Some(idx) = found else {
+            self.set_status("Last edited task no longer exists");
+            self.last_edit = None;
+            return;
+        };
+
+        self.clear_selection();
+        self.expand_section_containing(idx);
+        self.outline = false;
+        self.cursor = idx;
+        self.remember_edit(idx);
+        self.clamp_cursor_to_visible();
+        self.set_status(if self.cursor == idx {
+            "Jumped to last edit"
+        } else {
+            "Last edited task is hidden"
+        });
+    }
+}
@@ -29,6 +29,8 @@ pub fn map_key(event: KeyEvent) -> Key {
                 Key::Char(c)
             }
         }
+        // Terminals that report Ctrl+Enter at all mostly send Ctrl+j for it.
+        KeyCode::Enter if event.modifiers.contains(KeyModifiers::CONTROL) => Key::Ctrl('j'),
         KeyCode::Enter => Key::Enter,
         KeyCode::Esc => Key::Esc,
         KeyCode::Up => Key::Up,
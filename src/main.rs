@@ -1,37 +1,109 @@
+mod ansi;
 mod app;
+mod archive;
+mod bulk;
+mod cli;
+mod clipboard;
+mod color;
+mod command;
+mod comment;
+mod config;
+mod daily;
+mod date;
+mod date_picker;
 mod edit;
+mod export;
 mod external_edit;
+mod filter;
+mod fold;
+mod format;
+mod help;
+mod inbox;
 mod io;
+mod jump;
 mod keys;
 mod markdown;
+mod metadata;
 mod model;
+mod mouse;
+mod overlay;
+mod preview;
 mod render;
+mod safety;
+mod sections;
+mod sort;
+mod split;
+mod state;
+mod tabs;
 mod text_input;
+mod yank;
 
 use std::env;
 use std::fs;
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
 
 use log::LevelFilter;
 use simplelog::{Config, WriteLogger};
 
+use crate::cli::{
+    export, parse_done_args, parse_export_args, parse_summary_args, summary, toggle_by_text,
+};
+use crate::config::{load_config, Config as AppConfig};
+use crate::io::board_files;
+use crate::markdown::MarkdownStyle;
 use crate::model::App;
 
 fn main() {
-    let (logging_on, path, explicit_path) = parse_args();
-    if let Err(err) = init_logging(logging_on) {
+    let mut config = match load_config() {
+        Ok(config) => config,
+        Err(err) => {
+            eprintln!("invalid config: {}", err);
+            std::process::exit(1);
+        }
+    };
+
+    let args: Vec<String> = env::args().skip(1).collect();
+    if args.first().map(String::as_str) == Some("done") {
+        run_done(&args[1..], &config);
+    }
+    if args.iter().any(|arg| arg == "--summary") {
+        run_summary(&args, &config);
+    }
+    if args.iter().any(|arg| arg == "--export") {
+        run_export(&args, &config);
+    }
+
+    let args = parse_args(&config);
+    if let Some(style) = args.style.or_else(env_style) {
+        // An unknown name falls back to the automatic style.
+        config.display.style = MarkdownStyle::parse(&style).unwrap_or_default();
+    }
+    if args.plain {
+        config.display.markdown = false;
+    }
+    if let Err(err) = init_logging(args.logging_on || config.general.logs) {
         eprintln!("warning: failed to initialize logging: {}", err);
     }
 
-    let path = match resolve_path(path, explicit_path) {
-        Ok(path) => path,
+    let mut paths = match args
+        .paths
+        .into_iter()
+        .map(|path| resolve_path(path, args.no_create).and_then(check_openable))
+        .collect::<Result<Vec<_>, _>>()
+    {
+        Ok(paths) => paths,
         Err(err) => {
             eprintln!("{}", err);
             std::process::exit(1);
         }
     };
 
-    let app = match App::new(path) {
+    let first = paths.remove(0);
+    let app = App::new(first, config).and_then(|mut app| {
+        app.open_tabs(paths)?;
+        Ok(app)
+    });
+    let app = match app {
         Ok(app) => app,
         Err(err) => {
             eprintln!("failed to load file: {}", err);
@@ -45,42 +117,171 @@ fn main() {
     }
 }
 
-fn parse_args() -> (bool, PathBuf, bool) {
-    let mut logging_on = false;
-    let mut path: Option<PathBuf> = None;
+// `lazytodo done "text"` toggles a matching task and exits without the TUI.
+fn run_done(args: &[String], config: &AppConfig) -> ! {
+    let result = parse_done_args(args).and_then(|done| {
+        let explicit_path = done.path.is_some();
+        let path = done.path.unwrap_or_else(|| default_path(config));
+        let path = resolve_path(path, explicit_path)?;
+        toggle_by_text(
+            &path,
+            &done.text,
+            done.section.as_deref(),
+            config.format.done_mark,
+        )
+    });
+    match result {
+        Ok(summary) => {
+            println!("{}", summary);
+            std::process::exit(0);
+        }
+        Err(err) => {
+            eprintln!("{}", err);
+            std::process::exit(1);
+        }
+    }
+}
+
+// `lazytodo --summary` prints a one-line progress summary and exits.
+fn run_summary(args: &[String], config: &AppConfig) -> ! {
+    let result = parse_summary_args(args).and_then(|args| {
+        let explicit_path = args.path.is_some();
+        let path = args.path.unwrap_or_else(|| default_path(config));
+        let path = resolve_path(path, explicit_path)?;
+        summary(&path, &args.format)
+    });
+    match result {
+        Ok(line) => {
+            println!("{}", line);
+            std::process::exit(0);
+        }
+        Err(err) => {
+            eprintln!("{}", err);
+            std::process::exit(1);
+        }
+    }
+}
+
+// `lazytodo --export html|text` writes the list as a page or checklist and exits.
+fn run_export(args: &[String], config: &AppConfig) -> ! {
+    let result = parse_export_args(args).and_then(|args| {
+        let explicit_path = args.path.is_some();
+        let path = args.path.unwrap_or_else(|| default_path(config));
+        let path = resolve_path(path, explicit_path)?;
+        export(&path, args.format, args.output.as_deref())
+    });
+    match result {
+        Ok(line) => {
+            println!("{}", line);
+            std::process::exit(0);
+        }
+        Err(err) => {
+            eprintln!("{}", err);
+            std::process::exit(1);
+        }
+    }
+}
+
+// Command-line options for the interactive app.
+struct Args {
+    logging_on: bool,
+    paths: Vec<PathBuf>,
+    no_create: bool,
+    plain: bool,
+    // Markdown style name from --style, checked by the caller.
+    style: Option<String>,
+}
 
-    for arg in env::args().skip(1) {
+// Several paths open as tabs, in the order given. `--no-create` refuses
+// paths that don't exist instead of creating them on first save.
+fn parse_args(config: &AppConfig) -> Args {
+    let mut args = Args {
+        logging_on: false,
+        paths: Vec::new(),
+        no_create: false,
+        plain: false,
+        style: None,
+    };
+
+    let mut iter = env::args().skip(1);
+    while let Some(arg) = iter.next() {
         match arg.as_str() {
-            "--logs" | "-logs" => logging_on = true,
-            _ => {
-                if path.is_some() {
-                    eprintln!("usage: lazytodo [--logs] [path]");
-                    std::process::exit(1);
-                }
-                path = Some(PathBuf::from(arg));
-            }
+            "--logs" | "-logs" => args.logging_on = true,
+            "--no-create" => args.no_create = true,
+            "--plain" => args.plain = true,
+            "--style" => args.style = iter.next(),
+            _ => match arg.strip_prefix("--style=") {
+                Some(style) => args.style = Some(style.to_string()),
+                None => args.paths.push(PathBuf::from(arg)),
+            },
         }
     }
 
-    let explicit_path = path.is_some();
-    let path = path.unwrap_or_else(|| PathBuf::from("todo.md"));
-    (logging_on, path, explicit_path)
+    if args.paths.is_empty() {
+        args.paths.push(default_path(config));
+    }
+    args
+}
+
+fn env_style() -> Option<String> {
+    env::var("LAZYTODO_STYLE").ok().filter(|s| !s.is_empty())
 }
 
-fn resolve_path(path: PathBuf, explicit_path: bool) -> Result<PathBuf, String> {
-    if explicit_path {
-        if !path.exists() {
-            return Err(format!("file {} does not exist", path.display()));
+// File opened when no path is given: $LAZYTODO_FILE if set, else
+// `[general] file` (todo.md by default) in the current directory. Either is
+// created if missing.
+fn default_path(config: &AppConfig) -> PathBuf {
+    env::var_os("LAZYTODO_FILE")
+        .filter(|p| !p.is_empty())
+        .map(PathBuf::from)
+        .unwrap_or_else(|| PathBuf::from(&config.general.file))
+}
+
+// Absolute form of `path`. A missing file is created on first save unless
+// `must_exist`; any other failure to stat it is an error.
+fn resolve_path(path: PathBuf, must_exist: bool) -> Result<PathBuf, String> {
+    match fs::metadata(&path) {
+        Ok(_) => Ok(fs::canonicalize(&path).unwrap_or(path)),
+        Err(err) if err.kind() == std::io::ErrorKind::NotFound => {
+            if must_exist {
+                return Err(format!("file {} does not exist", path.display()));
+            }
+            env::current_dir()
+                .map(|cwd| cwd.join(path))
+                .map_err(|e| e.to_string())
         }
-        return Ok(fs::canonicalize(&path).unwrap_or(path));
+        Err(err) => Err(format!("{}: {}", path.display(), err)),
     }
+}
+
+// Catch paths the app can't load before the terminal is taken over: a
+// directory with no markdown files to show as a board, or a file or
+// directory we may not read. A missing file is fine; it's created on save.
+fn check_openable(path: PathBuf) -> Result<PathBuf, String> {
+    if path.is_dir() {
+        let files = board_files(&path).map_err(|err| open_error(&path, err))?;
+        if files.is_empty() {
+            return Err(format!(
+                "{}: path is a directory with no .md files to open as a board",
+                path.display()
+            ));
+        }
+        for file in &files {
+            fs::File::open(file).map_err(|err| open_error(file, err))?;
+        }
+        return Ok(path);
+    }
+    match fs::File::open(&path) {
+        Ok(_) => Ok(path),
+        Err(err) if err.kind() == std::io::ErrorKind::NotFound => Ok(path),
+        Err(err) => Err(open_error(&path, err)),
+    }
+}
 
-    if path.exists() {
-        Ok(fs::canonicalize(&path).unwrap_or(path))
-    } else {
-        env::current_dir()
-            .map(|cwd| cwd.join(path))
-            .map_err(|e| e.to_string())
+fn open_error(path: &Path, err: std::io::Error) -> String {
+    match err.kind() {
+        std::io::ErrorKind::PermissionDenied => format!("{}: permission denied", path.display()),
+        _ => format!("{}: {}", path.display(), err),
     }
 }
 
@@ -8,6 +8,30 @@ struct Style {
     code: bool,
 }
 
+// How inline markdown is styled. `Auto` sticks to bold, italic and reverse
+// video, which read the same on any background; `Dark` and `Light` color
+// code spans for that kind of terminal, and `NoTty` emits no escape codes.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum MarkdownStyle {
+    #[default]
+    Auto,
+    Dark,
+    Light,
+    NoTty,
+}
+
+impl MarkdownStyle {
+    pub fn parse(name: &str) -> Option<Self> {
+        match name.to_ascii_lowercase().as_str() {
+            "auto" => Some(Self::Auto),
+            "dark" => Some(Self::Dark),
+            "light" => Some(Self::Light),
+            "notty" => Some(Self::NoTty),
+            _ => None,
+        }
+    }
+}
+
 #[derive(Debug, Clone)]
 struct Segment {
     text: String,
@@ -16,7 +40,7 @@ struct Segment {
 
 // Minimal inline markdown renderer that outputs ANSI-styled text and wraps to width.
 // It intentionally favors simplicity over completeness for parity with the Go UI.
-pub fn render_markdown_line(raw: &str, width: usize) -> String {
+pub fn render_markdown_line(raw: &str, width: usize, theme: MarkdownStyle) -> String {
     if raw.trim().is_empty() {
         return String::new();
     }
@@ -69,15 +93,42 @@ pub fn render_markdown_line(raw: &str, width: usize) -> String {
         }
     }
 
-    let rendered = wrap_segments(&segments, width);
+    let rendered = wrap_segments(&segments, width, theme);
     rendered
         .trim_matches(|c| c == ' ' || c == '\n' || c == '\t')
         .to_string()
 }
 
-fn wrap_segments(segments: &[Segment], width: usize) -> String {
+// Raw task text wrapped to width without any styling, for `markdown = false`.
+pub fn render_plain_line(raw: &str, width: usize) -> String {
+    let segments = [Segment {
+        text: raw.to_string(),
+        style: Style::default(),
+    }];
+    wrap_segments(&segments, width, MarkdownStyle::NoTty)
+        .trim()
+        .to_string()
+}
+
+// Text content of inline markdown with all formatting markers removed.
+pub fn plain_text(raw: &str) -> String {
+    let mut options = Options::empty();
+    options.insert(Options::ENABLE_STRIKETHROUGH);
+
+    let mut out = String::new();
+    for event in Parser::new_ext(raw, options) {
+        match event {
+            Event::Text(text) | Event::Code(text) => out.push_str(&text),
+            Event::SoftBreak | Event::HardBreak => out.push(' '),
+            _ => {}
+        }
+    }
+    out.trim().to_string()
+}
+
+fn wrap_segments(segments: &[Segment], width: usize, theme: MarkdownStyle) -> String {
     if width == 0 {
-        return segments_to_string(segments);
+        return segments_to_string(segments, theme);
     }
 
     let mut lines: Vec<String> = Vec::new();
@@ -101,11 +152,12 @@ fn wrap_segments(segments: &[Segment], width: usize) -> String {
                 current_width = 0;
             }
 
-            if current_width == 0 && token == " " {
+            // Spaces never start a row or spill past the width.
+            if token == " " && (current_width == 0 || current_width + token_width > width) {
                 continue;
             }
 
-            current.push_str(&apply_style(&token, segment.style));
+            current.push_str(&apply_style(&token, segment.style, theme));
             current_width = current_width.saturating_add(token_width);
         }
     }
@@ -145,15 +197,18 @@ fn tokenize(text: &str) -> Vec<String> {
     tokens
 }
 
-fn segments_to_string(segments: &[Segment]) -> String {
+fn segments_to_string(segments: &[Segment], theme: MarkdownStyle) -> String {
     let mut out = String::new();
     for seg in segments {
-        out.push_str(&apply_style(&seg.text, seg.style));
+        out.push_str(&apply_style(&seg.text, seg.style, theme));
     }
     out
 }
 
-fn apply_style(text: &str, style: Style) -> String {
+fn apply_style(text: &str, style: Style, theme: MarkdownStyle) -> String {
+    if theme == MarkdownStyle::NoTty {
+        return text.to_string();
+    }
     let mut codes = Vec::new();
     if style.bold {
         codes.push("1");
@@ -162,7 +217,11 @@ fn apply_style(text: &str, style: Style) -> String {
         codes.push("3");
     }
     if style.code {
-        codes.push("7");
+        codes.push(match theme {
+            MarkdownStyle::Dark => "96",
+            MarkdownStyle::Light => "34",
+            _ => "7",
+        });
     }
 
     if codes.is_empty() {
@@ -0,0 +1,157 @@
+use once_cell::sync::Lazy;
+use regex::Regex;
+
+use crate::date::Date;
+
+// Inline metadata tokens embedded in task text (`!1`, `@done(...)`, ...).
+// Tokens live in the text itself so files round-trip untouched.
+
+// Groups: the whitespace before the token, its level, the whitespace after.
+static PRIORITY_RE: Lazy<Regex> =
+    Lazy::new(|| Regex::new(r"(^|\s)!([1-3])(\s|$)").expect("valid priority regex"));
+
+static DONE_RE: Lazy<Regex> =
+    Lazy::new(|| Regex::new(r"\s*@done\([^)]*\)").expect("valid done regex"));
+
+static DUE_RE: Lazy<Regex> = Lazy::new(|| Regex::new(r"@due\(([^)]*)\)").expect("valid due regex"));
+
+static TAG_RE: Lazy<Regex> =
+    Lazy::new(|| Regex::new(r"(?:^|\s)#([\w/-]+)").expect("valid tag regex"));
+
+static DUE_STRIP_RE: Lazy<Regex> =
+    Lazy::new(|| Regex::new(r"\s*@due\([^)]*\)").expect("valid due regex"));
+
+static DONE_DATE_RE: Lazy<Regex> =
+    Lazy::new(|| Regex::new(r"@done\(([^)]*)\)").expect("valid done regex"));
+
+static COLOR_RE: Lazy<Regex> =
+    Lazy::new(|| Regex::new(r"@color\(([^)]*)\)").expect("valid color regex"));
+
+static COLOR_STRIP_RE: Lazy<Regex> =
+    Lazy::new(|| Regex::new(r"\s*@color\([^)]*\)").expect("valid color regex"));
+
+// Priority from a `!1`..`!3` token, where 1 is the most urgent.
+pub fn priority(text: &str) -> Option<u8> {
+    PRIORITY_RE
+        .captures(text)
+        .and_then(|caps| caps.get(2))
+        .and_then(|m| m.as_str().parse().ok())
+}
+
+// Rewrite each `!1`..`!3` token with `f(token, priority)`, keeping the
+// whitespace around it.
+pub fn map_priority(text: &str, f: impl Fn(&str, u8) -> String) -> String {
+    PRIORITY_RE
+        .replace_all(text, |caps: &regex::Captures| {
+            let token = format!("!{}", &caps[2]);
+            let level = caps[2].parse().unwrap_or(3);
+            format!("{}{}{}", &caps[1], f(&token, level), &caps[3])
+        })
+        .into_owned()
+}
+
+// Remove any `@done(...)` completion timestamp from the text.
+pub fn strip_done(text: &str) -> String {
+    DONE_RE.replace_all(text, "").trim_start().to_string()
+}
+
+// Completion date from a `@done(YYYY-MM-DD)` token; anything after the date
+// (such as a time) is ignored.
+pub fn done_date(text: &str) -> Option<Date> {
+    let caps = DONE_DATE_RE.captures(text)?;
+    Date::parse(caps.get(1)?.as_str().split_whitespace().next()?)
+}
+
+// Append a `@done(stamp)` token unless the text already has one.
+pub fn stamp_done(text: &str, stamp: &str) -> String {
+    if DONE_DATE_RE.is_match(text) {
+        return text.to_string();
+    }
+    format!("{} @done({})", text.trim_end(), stamp)
+}
+
+// Rewrite each valid `@due(...)` token with `f(token, date)`; tokens whose
+// date doesn't parse are left alone.
+pub fn map_due(text: &str, mut f: impl FnMut(&str, Date) -> String) -> String {
+    DUE_RE
+        .replace_all(text, |caps: &regex::Captures| {
+            let token = &caps[0];
+            match Date::parse(&caps[1]) {
+                Some(date) => f(token, date),
+                None => token.to_string(),
+            }
+        })
+        .into_owned()
+}
+
+// Replace the task's `@due(...)` token with `date` in place, append one if
+// there is none, or remove it when `date` is None.
+pub fn set_due(text: &str, date: Option<Date>) -> String {
+    match date {
+        Some(date) if DUE_RE.is_match(text) => DUE_RE
+            .replace(text, format!("@due({})", date).as_str())
+            .into_owned(),
+        Some(date) if text.trim().is_empty() => format!("@due({})", date),
+        Some(date) => format!("{} @due({})", text.trim_end(), date),
+        None => DUE_STRIP_RE.replace_all(text, "").trim_start().to_string(),
+    }
+}
+
+// Due date from a `@due(YYYY-MM-DD)` token.
+pub fn due(text: &str) -> Option<Date> {
+    DUE_RE
+        .captures(text)
+        .and_then(|caps| caps.get(1))
+        .and_then(|m| Date::parse(m.as_str()))
+}
+
+// `#tag` tokens in the order they appear, without the leading `#`.
+pub fn tags(text: &str) -> Vec<String> {
+    TAG_RE
+        .captures_iter(text)
+        .filter_map(|caps| caps.get(1))
+        .map(|m| m.as_str().to_string())
+        .collect()
+}
+
+// Color name from a `@color(name)` token, as written.
+pub fn color(text: &str) -> Option<&str> {
+    COLOR_RE
+        .captures(text)
+        .and_then(|caps| caps.get(1))
+        .map(|m| m.as_str().trim())
+        .filter(|name| !name.is_empty())
+}
+
+// Replace the task's `@color(...)` token in place, append one if there is
+// none, or remove it when `name` is None.
+pub fn set_color(text: &str, name: Option<&str>) -> String {
+    match name {
+        Some(name) if COLOR_RE.is_match(text) => COLOR_RE
+            .replace(text, format!("@color({})", name).as_str())
+            .into_owned(),
+        Some(name) if text.trim().is_empty() => format!("@color({})", name),
+        Some(name) => format!("{} @color({})", text.trim_end(), name),
+        None => COLOR_STRIP_RE
+            .replace_all(text, "")
+            .trim_start()
+            .to_string(),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn priority_tokens() {
+        assert_eq!(priority("ship it !1"), Some(1));
+        assert_eq!(priority("!3 later"), Some(3));
+        assert_eq!(priority("wow!2 nope"), None);
+        assert_eq!(priority("!4"), None);
+        assert_eq!(
+            map_priority("a !1 b !2", |token, level| format!("<{}:{}>", token, level)),
+            "a <!1:1> b <!2:2>"
+        );
+    }
+}
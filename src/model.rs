@@ -1,6 +1,14 @@
+use std::cell::RefCell;
+use std::collections::{BTreeSet, HashMap};
 use std::path::PathBuf;
 use std::time::SystemTime;
 
+use crate::config::Config;
+use crate::date_picker::DatePicker;
+use crate::io::LineEnding;
+use crate::jump::LastEdit;
+use crate::metadata::{priority, stamp_done, strip_done};
+use crate::preview::Preview;
 use crate::text_input::TextInput;
 
 // Represents the current UI mode.
@@ -9,6 +17,21 @@ pub enum Mode {
     Normal,
     Edit,
     Search,
+    // Typing a `:` command in the footer.
+    Command,
+    Overlay(Overlay),
+}
+
+// Read-only full-screen views drawn instead of the list.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Overlay {
+    Focus,
+    Tags,
+    DatePicker,
+    // Diff of a pending bulk change, applied with y.
+    Preview,
+    // Every key binding, opened with `?`.
+    Help,
 }
 
 // Indicates whether we're updating an existing line or inserting a new one.
@@ -26,32 +49,102 @@ pub enum EditTarget {
     Section,
 }
 
+// Sweeping commands that wait for a y/n answer before running.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Confirm {
+    InvertAll,
+    ResetAll,
+    Reload,
+    Quit,
+}
+
+// Which tasks the list shows, cycled with `f`. Section headers always show.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum Filter {
+    #[default]
+    All,
+    Open,
+    Done,
+}
+
 #[derive(Debug, Clone, PartialEq, Eq)]
 pub struct Task {
     pub indent: String,
+    // `-`, `*`, `+` or an ordered marker like `1.` / `2)`, kept as written.
     pub bullet: String,
     pub completed: bool,
+    // Checkbox character of a completed task (`x` or `X`), kept as written.
+    pub mark: char,
     pub text: String,
+    // Subtasks hidden with `za`; view state only, never written to the file.
+    pub folded: bool,
+    // When loading moved `indent` onto the nesting grid: the indent as
+    // written and as loaded. The written one is saved back until `indent`
+    // is changed, so untouched lines aren't reindented on save.
+    pub file_indent: Option<(String, String)>,
 }
 
 impl Task {
+    // Priority from a `!1`..`!3` token in the text; 1 is the most urgent.
+    pub fn priority(&self) -> Option<u8> {
+        priority(&self.text)
+    }
+
+    // Complete (with `mark`) or reopen the task. With a `stamp`, completing
+    // it appends `@done(stamp)` and reopening it drops any `@done(...)`
+    // token. Tasks already in the requested state are left as they are, so
+    // a complete task keeps the mark it was written with.
+    pub fn set_completed(&mut self, completed: bool, mark: char, stamp: Option<&str>) {
+        if completed == self.completed {
+            return;
+        }
+        if completed {
+            self.mark = mark;
+        }
+        if let Some(stamp) = stamp {
+            self.text = if completed {
+                stamp_done(&self.text, stamp)
+            } else {
+                strip_done(&self.text)
+            };
+        }
+        self.completed = completed;
+    }
+
     pub fn line(&self) -> String {
-        let mark = if self.completed { "x" } else { " " };
-        format!("{}{} [{}] {}", self.indent, self.bullet, mark, self.text)
+        let indent = match &self.file_indent {
+            Some((written, loaded)) if *loaded == self.indent => written,
+            _ => &self.indent,
+        };
+        let mark = if self.completed { self.mark } else { ' ' };
+        format!("{}{} [{}] {}", indent, self.bullet, mark, self.text)
     }
 }
 
 #[derive(Debug, Clone, PartialEq, Eq)]
 pub enum LineItem {
     Task(Task),
-    Section { title: String },
+    // `collapsed` is view state only and never written to disk.
+    Section { title: String, collapsed: bool },
+    // Marks where a file's lines begin when a directory is opened as a board.
+    File { path: PathBuf },
+    // A `---` horizontal rule separating groups of tasks.
+    Rule,
+    // An HTML comment line (`<!-- ... -->`), kept verbatim and hidden unless
+    // comments are shown.
+    Comment { text: String },
+    // Any other line (prose, blank lines, tables, ...), written back verbatim.
+    Raw { text: String },
 }
 
 impl LineItem {
     pub fn line(&self) -> String {
         match self {
-            LineItem::Section { title } => format!("## {}", title),
+            LineItem::Section { title, .. } => format!("## {}", title),
             LineItem::Task(task) => task.line(),
+            LineItem::File { .. } => String::new(),
+            LineItem::Rule => "---".to_string(),
+            LineItem::Comment { text } | LineItem::Raw { text } => text.clone(),
         }
     }
 
@@ -62,6 +155,27 @@ impl LineItem {
     pub fn is_section(&self) -> bool {
         matches!(self, LineItem::Section { .. })
     }
+
+    pub fn is_file(&self) -> bool {
+        matches!(self, LineItem::File { .. })
+    }
+
+    pub fn is_comment(&self) -> bool {
+        matches!(self, LineItem::Comment { .. })
+    }
+
+    // Blank passthrough lines, which j/k step over.
+    pub fn is_blank(&self) -> bool {
+        matches!(self, LineItem::Raw { text } if text.trim().is_empty())
+    }
+}
+
+// (completed, total) task counts across `lines`.
+pub fn task_progress(lines: &[LineItem]) -> (usize, usize) {
+    lines.iter().fold((0, 0), |(done, total), line| match line {
+        LineItem::Task(task) => (done + usize::from(task.completed), total + 1),
+        _ => (done, total),
+    })
 }
 
 #[derive(Debug, Clone)]
@@ -70,10 +184,25 @@ pub struct UndoState {
     pub cursor: usize,
 }
 
+// Per-file state swapped in and out of `App` when switching between files.
+#[derive(Debug)]
+pub struct BufferState {
+    pub file_path: PathBuf,
+    pub lines: Vec<LineItem>,
+    pub cursor: usize,
+    pub scroll_offset: usize,
+    pub last_modified: SystemTime,
+    pub undo_stack: Vec<UndoState>,
+    pub redo_stack: Vec<UndoState>,
+    pub dirty: bool,
+    pub indent_unit: String,
+    pub line_ending: LineEnding,
+}
+
 pub const MAX_UNDO_HISTORY: usize = 10;
 
-// Indentation levels (4 states: none, 4, 8, 12 spaces)
-pub const INDENT_LEVELS: [&str; 4] = ["", "    ", "        ", "            "];
+// Deepest nesting level; 0 is top level, so there are 4 levels in all.
+pub const MAX_INDENT_LEVEL: usize = 3;
 
 #[derive(Debug)]
 pub struct App {
@@ -83,25 +212,81 @@ pub struct App {
     pub mode: Mode,
     pub text_input: TextInput,
     pub search_input: TextInput,
+    pub command_input: TextInput,
     pub input_placeholder: String,
     pub edit_intent: EditIntent,
     pub edit_target: EditTarget,
     pub edit_index: Option<usize>,
     pub insert_index: Option<usize>,
     pub edit_template: Task,
+    // Wrap the inline editor over several rows instead of scrolling it (ctrl+w).
+    pub edit_wrap: bool,
+    // Set through set_status, which bumps `status_seq`.
     pub status_message: String,
+    // Counts status updates, so a repeated message still counts as new.
+    pub status_seq: u64,
     pub error: Option<String>,
     pub last_modified: SystemTime,
     pub pending_reload: bool,
+    // In-memory changes that failed to save and differ from the file.
+    pub dirty: bool,
     pub selection_active: bool,
     pub selection_anchor: usize,
+    // Individually marked lines (`m`), selected alongside any `V` range.
+    pub marked: BTreeSet<usize>,
     pub window_width: u16,
     pub window_height: u16,
     pub renderer_width: usize,
+    // Styled task text keyed by (raw text, wrap width), so unchanged tasks
+    // aren't re-rendered every frame. An edited task has a new key.
+    pub markdown_cache: RefCell<HashMap<(String, usize), String>>,
     pub external_edit_idx: Option<usize>,
     pub undo_stack: Vec<UndoState>,
     pub redo_stack: Vec<UndoState>,
-    pub pending_d: bool,
+    pub register: Vec<LineItem>,
+    // First key of a two-key command (e.g. the `d` of `dd`).
+    pub pending_key: Option<char>,
+    pub pending_confirm: Option<Confirm>,
+    // Numeric prefix typed before a command (the 3 in `3dd`).
+    pub pending_count: Option<usize>,
     pub scroll_offset: usize,
+    pub overlay_scroll: usize,
     pub should_quit: bool,
+    pub outline: bool,
+    // Render `@due` dates relative to today (toggled with ctrl+d).
+    pub relative_dates: bool,
+    // Style inline markdown in task text; off shows it raw (toggled with ctrl+t).
+    pub markdown: bool,
+    // State of the `@` due date picker while it is open.
+    pub date_picker: Option<DatePicker>,
+    // Bulk change shown in the preview overlay until it is confirmed.
+    pub preview: Option<Preview>,
+    // Open and done tasks in side-by-side columns (toggled with `|`).
+    pub split_view: bool,
+    // Show comment lines, which are otherwise hidden (toggled with `H`).
+    pub show_comments: bool,
+    // Hide completed (or open) tasks; view state only.
+    pub filter: Filter,
+    // What one nesting level adds: the file's own unit, detected on load,
+    // or `[format] indent_width` spaces when nothing is nested yet.
+    pub indent_unit: String,
+    // CRLF or LF, as the file had it; saves write the same.
+    pub line_ending: LineEnding,
+    // Show only tasks carrying this `#tag` (picked in the `%` overlay).
+    pub tag_filter: Option<String>,
+    // Highlighted row of the `%` tag picker.
+    pub tag_cursor: usize,
+    // Line shown on each screen row by the last render (None for the header,
+    // footer and editor rows), used to map mouse clicks back to lines.
+    pub screen_rows: Vec<Option<usize>>,
+    // Task the backtick-backtick jump returns to.
+    pub last_edit: Option<LastEdit>,
+    pub config: Config,
+    pub stashed_buffer: Option<BufferState>,
+    pub inbox_active: bool,
+    // Files opened together on the command line. The active tab's slot is
+    // None because its state lives in the fields above; empty when only one
+    // file is open.
+    pub tabs: Vec<Option<BufferState>>,
+    pub active_tab: usize,
 }
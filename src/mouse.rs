@@ -0,0 +1,26 @@
+use crate::model::{App, LineItem, Mode};
+use crate::render::checkbox_columns;
+
+impl App {
+    // Left click in the list: move the cursor to the clicked line, and toggle
+    // the task when the click lands on its `[ ]`. Ignored outside normal mode.
+    pub fn handle_click(&mut self, column: usize, row: usize) {
+        if self.mode != Mode::Normal {
+            return;
+        }
+        let Some(&Some(idx)) = self.screen_rows.get(row) else {
+            return;
+        };
+        // Wrapped tasks span several rows; the checkbox is only on the first.
+        let first_row = row == 0 || self.screen_rows[row - 1] != Some(idx);
+        self.pending_key = None;
+        self.pending_count = None;
+        self.clear_selection();
+        self.cursor = idx;
+        if let Some(LineItem::Task(task)) = self.lines.get(idx) {
+            if first_row && checkbox_columns(task).contains(&column) {
+                self.toggle_tasks();
+            }
+        }
+    }
+}
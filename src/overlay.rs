@@ -0,0 +1,257 @@
+use crate::date::Date;
+use crate::date_picker::{DatePicker, PickerAction};
+use crate::keys::Key;
+use crate::metadata::{due, set_due, tags};
+use crate::model::{App, LineItem, Mode, Overlay};
+
+const TAG_BAR_WIDTH: usize = 20;
+
+impl App {
+    pub fn open_overlay(&mut self, overlay: Overlay) {
+        self.pending_key = None;
+        self.overlay_scroll = 0;
+        self.mode = Mode::Overlay(overlay);
+    }
+
+    pub fn open_focus(&mut self) {
+        if !matches!(self.lines.get(self.cursor), Some(LineItem::Task(_))) {
+            self.set_status("No task to focus");
+            return;
+        }
+        self.open_overlay(Overlay::Focus);
+    }
+
+    // Open the calendar on the task's current due date, or today.
+    pub fn open_date_picker(&mut self) {
+        let Some(LineItem::Task(task)) = self.lines.get(self.cursor) else {
+            self.set_status("No task to date");
+            return;
+        };
+        let today = Date::today();
+        let current = due(&task.text).unwrap_or(today);
+        self.date_picker = Some(DatePicker::new(current, today));
+        self.open_overlay(Overlay::DatePicker);
+    }
+
+    fn handle_date_picker_key(&mut self, key: Key) {
+        let Some(picker) = self.date_picker.as_mut() else {
+            self.mode = Mode::Normal;
+            return;
+        };
+        let due_date = match picker.handle_key(key) {
+            PickerAction::None => return,
+            PickerAction::Cancel => {
+                self.close_date_picker();
+                return;
+            }
+            PickerAction::Pick(date) => Some(date),
+            PickerAction::Clear => None,
+        };
+        self.close_date_picker();
+
+        let Some(LineItem::Task(task)) = self.lines.get(self.cursor) else {
+            return;
+        };
+        let text = set_due(&task.text, due_date);
+        if text == task.text {
+            return;
+        }
+        self.save_undo_state();
+        if let Some(LineItem::Task(task)) = self.lines.get_mut(self.cursor) {
+            task.text = text;
+        }
+        self.remember_edit(self.cursor);
+        let msg = match due_date {
+            Some(date) => format!("Due {}", date),
+            None => "Due date cleared".to_string(),
+        };
+        self.save_and_set_status(&msg);
+    }
+
+    fn close_date_picker(&mut self) {
+        self.date_picker = None;
+        self.mode = Mode::Normal;
+    }
+
+    pub(crate) fn handle_overlay_key(&mut self, key: Key) {
+        let Mode::Overlay(overlay) = self.mode else {
+            return;
+        };
+        if overlay == Overlay::DatePicker {
+            self.handle_date_picker_key(key);
+            return;
+        }
+        if overlay == Overlay::Preview && self.handle_preview_key(key) {
+            return;
+        }
+        let max_scroll = self
+            .overlay_lines(overlay)
+            .len()
+            .saturating_sub(self.overlay_body_height());
+        if overlay == Overlay::Help {
+            self.handle_help_key(key, max_scroll);
+            return;
+        }
+        if overlay == Overlay::Tags {
+            self.handle_tag_picker_key(key);
+            return;
+        }
+        match key {
+            Key::Esc | Key::Char('q') | Key::Ctrl('c') => self.mode = Mode::Normal,
+            Key::Char('j') | Key::Down => {
+                self.overlay_scroll = (self.overlay_scroll + 1).min(max_scroll)
+            }
+            Key::Char('k') | Key::Up => self.overlay_scroll = self.overlay_scroll.saturating_sub(1),
+            Key::Char('g') | Key::Home => self.overlay_scroll = 0,
+            Key::Char('G') | Key::End => self.overlay_scroll = max_scroll,
+            _ => {}
+        }
+    }
+
+    pub(crate) fn render_overlay(&self, overlay: Overlay) -> String {
+        let lines = self.overlay_lines(overlay);
+        let height = self.overlay_body_height();
+        let start = self.overlay_scroll.min(lines.len());
+        let end = (start + height).min(lines.len());
+
+        let mut out = format!("\x1b[1m{}\x1b[0m\n\n", overlay_title(overlay));
+        for line in &lines[start..end] {
+            out.push_str("  ");
+            out.push_str(line);
+            out.push('\n');
+        }
+        let position = if lines.len() > height {
+            format!(" · {}-{}/{}", start + 1, end, lines.len())
+        } else {
+            String::new()
+        };
+        out.push_str(&format!("\n{}{}\n", overlay_hints(overlay), position));
+        out
+    }
+
+    fn overlay_lines(&self, overlay: Overlay) -> Vec<String> {
+        match overlay {
+            Overlay::Focus => match self.lines.get(self.cursor) {
+                Some(LineItem::Task(task)) => {
+                    let width = (self.window_width as usize).saturating_sub(4);
+                    let checkbox = if task.completed { "[x]" } else { "[ ]" };
+                    let mut lines = vec![checkbox.to_string(), String::new()];
+                    lines.extend(
+                        self.render_task_text(&task.text, width)
+                            .split('\n')
+                            .map(str::to_string),
+                    );
+                    lines
+                }
+                _ => Vec::new(),
+            },
+            Overlay::Tags => self.tag_progress_lines(),
+            Overlay::Preview => self.preview_lines(),
+            Overlay::Help => self.help_lines(),
+            Overlay::DatePicker => self
+                .date_picker
+                .map(|picker| picker.render())
+                .unwrap_or_default(),
+        }
+    }
+
+    // Each `#tag` with its done and total task counts, most-used first.
+    pub(crate) fn tag_counts(&self) -> Vec<(String, usize, usize)> {
+        let mut counts: Vec<(String, usize, usize)> = Vec::new();
+        for line in &self.lines {
+            let LineItem::Task(task) = line else {
+                continue;
+            };
+            let mut seen = tags(&task.text);
+            seen.sort();
+            seen.dedup();
+            for tag in seen {
+                let pos = match counts.iter().position(|(name, _, _)| *name == tag) {
+                    Some(pos) => pos,
+                    None => {
+                        counts.push((tag, 0, 0));
+                        counts.len() - 1
+                    }
+                };
+                counts[pos].2 += 1;
+                if task.completed {
+                    counts[pos].1 += 1;
+                }
+            }
+        }
+        counts.sort_by(|a, b| b.2.cmp(&a.2).then_with(|| a.0.cmp(&b.0)));
+        counts
+    }
+
+    // One row per tag: name, a completion bar, and done/total. The picked
+    // row is highlighted and the active filter marked.
+    fn tag_progress_lines(&self) -> Vec<String> {
+        let counts = self.tag_counts();
+        if counts.is_empty() {
+            return vec!["No #tags found".to_string()];
+        }
+
+        let name_width = counts
+            .iter()
+            .map(|(name, _, _)| name.chars().count())
+            .max()
+            .unwrap_or(0);
+        counts
+            .iter()
+            .enumerate()
+            .map(|(i, (name, done, total))| {
+                let filled = done * TAG_BAR_WIDTH / total;
+                let active = if self.tag_filter.as_ref() == Some(name) {
+                    "  (filtering)"
+                } else {
+                    ""
+                };
+                let row = format!(
+                    "#{:<width$}  {}{}  {}/{}{}",
+                    name,
+                    "█".repeat(filled),
+                    "░".repeat(TAG_BAR_WIDTH - filled),
+                    done,
+                    total,
+                    active,
+                    width = name_width
+                );
+                if i == self.tag_cursor {
+                    format!("\x1b[7m{}\x1b[0m", row)
+                } else {
+                    row
+                }
+            })
+            .collect()
+    }
+
+    // Rows left for overlay content after the title and footer.
+    pub(crate) fn overlay_body_height(&self) -> usize {
+        if self.window_height == 0 {
+            return usize::MAX;
+        }
+        (self.window_height as usize).saturating_sub(4).max(1)
+    }
+}
+
+fn overlay_title(overlay: Overlay) -> &'static str {
+    match overlay {
+        Overlay::Focus => "Focus",
+        Overlay::Tags => "Progress by tag",
+        Overlay::DatePicker => "Due date",
+        Overlay::Preview => "Preview",
+        Overlay::Help => "Key bindings",
+    }
+}
+
+fn overlay_hints(overlay: Overlay) -> &'static str {
+    match overlay {
+        Overlay::DatePicker => {
+            "h/l day · j/k week · H/L month · t today · m tomorrow · w next week · x clear · Enter set · Esc cancel"
+        }
+        Overlay::Preview => "y apply · n cancel · j/k scroll",
+        Overlay::Help => "j/k scroll · any other key closes",
+        Overlay::Tags => "j/k pick · Enter show only its tasks · x show all · Esc close",
+        _ => "j/k scroll · Esc close",
+    }
+}
@@ -0,0 +1,109 @@
+use crate::keys::Key;
+use crate::model::{App, LineItem, Mode, Overlay};
+
+const REMOVED_ON: &str = "\x1b[31m";
+const ADDED_ON: &str = "\x1b[32m";
+const COLOR_OFF: &str = "\x1b[39m";
+
+// A bulk change waiting in the preview overlay for a y/n answer.
+#[derive(Debug, Clone)]
+pub struct Preview {
+    pub lines: Vec<LineItem>,
+    pub message: String,
+    pub diff: Vec<String>,
+    // Where the cursor goes once the change is applied.
+    pub cursor: usize,
+}
+
+impl App {
+    // Replace every line with `lines` as one undoable step, or show the
+    // change for review first when `[bulk] preview` is on.
+    pub(crate) fn apply_bulk(&mut self, lines: Vec<LineItem>, msg: &str) {
+        self.apply_bulk_at(lines, msg, self.cursor);
+    }
+
+    // apply_bulk for changes that move lines around, putting the cursor on
+    // `cursor` (an index into `lines`) once applied.
+    pub(crate) fn apply_bulk_at(&mut self, lines: Vec<LineItem>, msg: &str, cursor: usize) {
+        if self.config.bulk.preview {
+            self.preview = Some(Preview {
+                diff: diff_lines(&self.lines, &lines),
+                lines,
+                message: msg.to_string(),
+                cursor,
+            });
+            self.open_overlay(Overlay::Preview);
+            return;
+        }
+        self.save_undo_state();
+        self.clear_selection();
+        self.lines = lines;
+        self.cursor = cursor;
+        self.save_and_set_status(msg);
+    }
+
+    // y or Enter applies the previewed change; n, q or Esc drops it.
+    pub(crate) fn handle_preview_key(&mut self, key: Key) -> bool {
+        match key {
+            Key::Char('y') | Key::Char('Y') | Key::Enter => {
+                self.mode = Mode::Normal;
+                if let Some(preview) = self.preview.take() {
+                    self.save_undo_state();
+                    self.clear_selection();
+                    self.lines = preview.lines;
+                    self.cursor = preview.cursor;
+                    self.clamp_cursor_to_visible();
+                    self.save_and_set_status(&preview.message);
+                }
+                true
+            }
+            Key::Char('n') | Key::Char('N') | Key::Char('q') | Key::Esc | Key::Ctrl('c') => {
+                self.mode = Mode::Normal;
+                self.preview = None;
+                self.set_status("Canceled");
+                true
+            }
+            _ => false,
+        }
+    }
+
+    pub(crate) fn preview_lines(&self) -> Vec<String> {
+        let Some(preview) = &self.preview else {
+            return Vec::new();
+        };
+        let changed = preview.diff.iter().filter(|l| l.contains(ADDED_ON)).count();
+        let mut out = vec![
+            preview.message.clone(),
+            format!("{} lines change", changed),
+            String::new(),
+        ];
+        out.extend(preview.diff.iter().cloned());
+        out
+    }
+}
+
+// Line-by-line diff of two versions of the file: each changed line as a
+// red `-` row followed by a green `+` row, prefixed with its line number.
+fn diff_lines(old: &[LineItem], new: &[LineItem]) -> Vec<String> {
+    let mut out = Vec::new();
+    for i in 0..old.len().max(new.len()) {
+        let before = old.get(i).map(LineItem::line);
+        let after = new.get(i).map(LineItem::line);
+        if before == after {
+            continue;
+        }
+        if let Some(before) = before {
+            out.push(format!(
+                "{:>4} {}- {}{}",
+                i + 1,
+                REMOVED_ON,
+                before,
+                COLOR_OFF
+            ));
+        }
+        if let Some(after) = after {
+            out.push(format!("{:>4} {}+ {}{}", i + 1, ADDED_ON, after, COLOR_OFF));
+        }
+    }
+    out
+}
@@ -1,53 +1,83 @@
 use std::path::Path;
 
-use once_cell::sync::Lazy;
-use regex::Regex;
-
-use crate::markdown::render_markdown_line;
-use crate::model::{App, EditIntent, EditTarget, LineItem, Mode, Task};
+use crate::ansi::{strip_ansi, truncate_visible, visible_width};
+use crate::color::{color_code, COLOR_OFF};
+use crate::date::Date;
+use crate::markdown::{render_markdown_line, render_plain_line};
+use crate::metadata::{color, map_due, map_priority};
+use crate::model::{
+    task_progress, App, EditIntent, EditTarget, LineItem, Mode, Task, MAX_UNDO_HISTORY,
+};
 
 const WRAP_MARGIN: usize = 6;
+// Start the markdown cache over once it holds this many renders, so text
+// from old edits doesn't pile up.
+const MARKDOWN_CACHE_LIMIT: usize = 4096;
+// Deeply nested tasks still wrap at no fewer than this many columns.
+const MIN_TEXT_WIDTH: usize = 10;
+
+const EMPTY_HINTS: [&str; 5] = [
+    "o   add a task",
+    "S   add a section",
+    "T   start today's log",
+    "I   open the inbox",
+    "q   quit",
+];
 
 // Bright background highlight for visual selection (rough parity with Go).
-const HIGHLIGHT_ON: &str = "\x1b[48;5;226m\x1b[30m";
+pub(crate) const HIGHLIGHT_ON: &str = "\x1b[48;5;226m\x1b[30m";
 const HIGHLIGHT_OFF: &str = "\x1b[0m";
 const MATCH_ON: &str = "\x1b[48;5;24m\x1b[38;5;15m";
 const MATCH_OFF: &str = "\x1b[49m\x1b[39m";
-const CLEAR_TO_EOL: &str = "\x1b[K";
-
-static ANSI_ESCAPE_RE: Lazy<Regex> =
-    Lazy::new(|| Regex::new(r"\x1b\[[0-9;]*m").expect("valid ansi regex"));
+const DIM_ON: &str = "\x1b[2m";
+const DIM_OFF: &str = "\x1b[22m";
+const RED_ON: &str = "\x1b[1;31m";
+const RED_OFF: &str = "\x1b[22;39m";
+const YELLOW_ON: &str = "\x1b[33m";
+const YELLOW_OFF: &str = "\x1b[39m";
+// Colors of `!1` and `!2` tokens on open tasks; `!3` stays plain.
+const PRIORITY_COLORS: [(&str, &str); 2] = [(RED_ON, RED_OFF), (YELLOW_ON, YELLOW_OFF)];
+// Width limits of the progress bar drawn after section titles.
+const SECTION_BAR_MIN: usize = 5;
+const SECTION_BAR_MAX: usize = 20;
+// Due dates this many days out (or fewer) render as "soon".
+const DUE_SOON_DAYS: i64 = 2;
 
 impl App {
     pub fn render(&mut self) -> String {
+        self.screen_rows.clear();
+        if let Mode::Overlay(overlay) = self.mode {
+            return pad_view_to_window(self.render_overlay(overlay), self.window_height);
+        }
+        if self.split_view && self.mode != Mode::Edit {
+            return pad_view_to_window(self.render_split(), self.window_height);
+        }
+
         let mut out = String::new();
-        let header = render_header(&self.file_path);
+        let header = self.header();
         out.push_str(&header);
 
         let filter_active = self.search_active() && self.mode != Mode::Edit;
+        self.clamp_cursor_to_visible();
         let visible_indices = self.visible_indices();
-        if filter_active {
-            self.ensure_cursor_visible_in(&visible_indices);
-        }
 
         let show_empty_state = self.count_tasks() == 0
             && !(self.mode == Mode::Edit
                 && self.edit_intent == EditIntent::Insert
                 && self.edit_target == EditTarget::Task);
         let show_no_matches = filter_active && visible_indices.is_empty() && !show_empty_state;
-        if show_empty_state {
-            out.push_str("No tasks found. Press 'o' to create one.\n");
+        let placeholder = if show_empty_state {
+            self.empty_state()
         } else if show_no_matches {
-            out.push_str("No matches. Press Esc to clear search.\n");
-        }
+            "No matches. Press Esc to clear search.\n".to_string()
+        } else {
+            String::new()
+        };
+        out.push_str(&placeholder);
 
         let footer = self.render_footer();
         let header_lines = count_lines(&header);
-        let empty_lines = if show_empty_state || show_no_matches {
-            1
-        } else {
-            0
-        };
+        let empty_lines = placeholder.matches('\n').count();
         let footer_lines = count_lines(&footer);
         let available_items = if self.window_height == 0 {
             usize::MAX
@@ -78,22 +108,18 @@ impl App {
                 .unwrap_or(0)
         };
 
-        self.ensure_scroll(total_items, available_items, cursor_pos);
-        let start = self.scroll_offset.min(total_items);
-        let end = if available_items == usize::MAX {
-            total_items
-        } else {
-            (start + available_items).min(total_items)
-        };
-
-        for view_pos in start..end {
+        // Render every row first: wrapped tasks take several screen rows, so
+        // the window around the cursor is measured in rows, not items.
+        let mut items: Vec<(Option<usize>, String)> = Vec::with_capacity(total_items);
+        for view_pos in 0..total_items {
             if let Some(edit_pos) = editor_pos {
                 if view_pos == edit_pos {
-                    if self.edit_target == EditTarget::Section {
-                        out.push_str(&self.render_section_editor_line(view_pos));
+                    let piece = if self.edit_target == EditTarget::Section {
+                        self.render_section_editor_line(view_pos)
                     } else {
-                        out.push_str(&self.render_editor_line(&self.edit_template, view_pos));
-                    }
+                        self.render_editor_line(&self.edit_template, view_pos)
+                    };
+                    items.push((None, piece));
                     continue;
                 }
             }
@@ -112,11 +138,14 @@ impl App {
                 && self.edit_intent == EditIntent::Update
                 && self.edit_index == Some(idx)
             {
-                if self.edit_target == EditTarget::Section {
-                    out.push_str(&self.render_section_editor_line(idx));
+                let piece = if self.edit_target == EditTarget::Section {
+                    self.render_section_editor_line(idx)
                 } else if let LineItem::Task(task) = &self.lines[idx] {
-                    out.push_str(&self.render_editor_line(task, idx));
-                }
+                    self.render_editor_line(task, idx)
+                } else {
+                    String::new()
+                };
+                items.push((None, piece));
                 continue;
             }
 
@@ -124,86 +153,298 @@ impl App {
                 && self.edit_intent == EditIntent::Insert
                 && self.edit_index == Some(idx);
 
-            match &self.lines[idx] {
-                LineItem::Section { title } => {
-                    out.push_str(&self.render_section_line(title, idx, suppress_cursor));
-                }
-                LineItem::Task(task) => {
-                    out.push_str(&self.render_task_line(task, idx, suppress_cursor));
+            let piece = match &self.lines[idx] {
+                LineItem::Section { title, collapsed } => {
+                    self.render_section_line(title, *collapsed, idx, suppress_cursor)
                 }
+                LineItem::Task(task) => self.render_task_line(task, idx, suppress_cursor),
+                LineItem::File { path } => self.render_file_line(path, idx, suppress_cursor),
+                LineItem::Rule => self.render_rule_line(idx, suppress_cursor),
+                LineItem::Comment { text } => self.render_comment_line(text, idx, suppress_cursor),
+                LineItem::Raw { text } => format_section_line(self, idx, suppress_cursor, text),
+            };
+            items.push((Some(idx), piece));
+        }
+
+        let heights: Vec<usize> = items
+            .iter()
+            .map(|(_, piece)| piece.matches('\n').count().max(1))
+            .collect();
+        self.ensure_scroll(&heights, available_items, cursor_pos);
+        let start = self.scroll_offset.min(total_items);
+        let mut end = start;
+        let mut used = 0;
+        // Always show the cursor's item, even if it alone overflows.
+        while end < total_items && (end == start || used + heights[end] <= available_items) {
+            used += heights[end];
+            end += 1;
+        }
+
+        for (line, piece) in &items[start..end] {
+            let first_row = out.matches('\n').count();
+            out.push_str(piece);
+            if let Some(idx) = line {
+                let rows = out.matches('\n').count();
+                self.screen_rows.resize(first_row, None);
+                self.screen_rows.resize(rows, Some(*idx));
             }
         }
 
+        let footer = if end - start < total_items {
+            self.render_footer_at(Some((start + 1, end, total_items)))
+        } else {
+            footer
+        };
         out.push_str(&footer);
         pad_view_to_window(out, self.window_height)
     }
 
+    // Tab bar and "Managing todo.md" line, with overall progress when
+    // `[display] header_progress` is on.
+    pub(crate) fn header(&self) -> String {
+        let progress = self
+            .config
+            .display
+            .header_progress
+            .then(|| task_progress(&self.lines));
+        format!(
+            "{}{}",
+            self.tab_bar(),
+            render_header(&self.file_path, self.overdue_count(Date::today()), progress)
+        )
+    }
+
+    // Task text as shown in the list and focus view: styled inline markdown,
+    // or the raw text when markdown is off (`--plain`, ctrl+t).
+    pub(crate) fn render_task_text(&self, raw: &str, width: usize) -> String {
+        if !self.markdown {
+            return render_plain_line(raw, width);
+        }
+        let key = (raw.to_string(), width);
+        if let Some(rendered) = self.markdown_cache.borrow().get(&key) {
+            return rendered.clone();
+        }
+        let rendered = render_markdown_line(raw, width, self.config.display.style);
+        let mut cache = self.markdown_cache.borrow_mut();
+        if cache.len() >= MARKDOWN_CACHE_LIMIT {
+            cache.clear();
+        }
+        cache.insert(key, rendered.clone());
+        rendered
+    }
+
     fn render_task_line(&self, task: &Task, index: usize, suppress_cursor: bool) -> String {
-        let mut body = render_markdown_line(&task.text, self.renderer_width);
+        let indent = task.indent.replace('\t', "    ");
+        let mut checkbox = checkbox_symbol(task.completed).to_string();
+        if self.task_block_end(index) > index + 1 {
+            checkbox.push_str(if task.folded { " ▸" } else { " ▾" });
+        }
+        let hang = visible_width(&indent) + visible_width(&checkbox) + 1;
+        if let Some(code) = color(&task.text).and_then(color_code) {
+            checkbox = format!("{}{}{}", code, checkbox, COLOR_OFF);
+        }
+
+        // renderer_width only leaves WRAP_MARGIN columns for the gutter and
+        // checkbox; wrap earlier by whatever the indent and fold marker add
+        // beyond that. A zero width means no wrapping.
+        let reserved = WRAP_MARGIN - visible_width(gutter(false));
+        let width = match self.renderer_width {
+            0 => 0,
+            width => width
+                .saturating_sub(hang.saturating_sub(reserved))
+                .max(MIN_TEXT_WIDTH),
+        };
+        let mut body = self.render_task_text(&task.text, width);
+        body = self.decorate_due_dates(&body, task.completed);
+        if !task.completed {
+            body = decorate_priority(&body);
+        }
         if self.search_active() && self.mode != Mode::Edit {
             body = highlight_matches(&body, self.search_query());
         }
-        let indent = task.indent.replace('\t', "    ");
-        let checkbox = checkbox_symbol(task.completed);
-
-        let mut lines = body.split('\n').collect::<Vec<_>>();
-        if lines.is_empty() {
-            lines.push("");
+        if let Some((done, total)) = self.child_progress(index) {
+            body.push_str(&format!(" {}({}/{}){}", DIM_ON, done, total, DIM_OFF));
+        }
+        // Characters, not bytes, so emoji and accents count once.
+        let length = task.text.chars().count();
+        let limit = self.config.display.task_length;
+        if limit > 0 && length > limit {
+            body.push_str(&format!(" {}({}){}", YELLOW_ON, length, YELLOW_OFF));
         }
 
-        let prefix = format!("{}{} ", indent, checkbox);
-        let cont_prefix = format!("{}{}", indent, " ".repeat(checkbox.len() + 1));
-        let mut rendered = String::new();
-        for (i, line) in lines.iter().enumerate() {
-            if i > 0 {
-                rendered.push('\n');
-            }
-            if i == 0 {
-                rendered.push_str(&prefix);
+        let rendered = format!("{}{} {}", indent, checkbox, body);
+        format_line(self, index, false, suppress_cursor, hang, &rendered)
+    }
+
+    // Color `@due` tokens (red when past, yellow within two days) and, in
+    // relative mode, show them as "due in 2 days". The stored text is untouched.
+    fn decorate_due_dates(&self, body: &str, completed: bool) -> String {
+        let today = Date::today();
+        map_due(body, |token, date| {
+            let label = if self.relative_dates {
+                format!("due {}", date.relative_to(today))
             } else {
-                rendered.push_str(&cont_prefix);
+                token.to_string()
+            };
+            let days = date.days_since(today);
+            if completed {
+                label
+            } else if days < 0 {
+                format!("{}{}{}", RED_ON, label, RED_OFF)
+            } else if days <= DUE_SOON_DAYS {
+                format!("{}{}{}", YELLOW_ON, label, YELLOW_OFF)
+            } else {
+                label
             }
-            rendered.push_str(line);
-        }
-        format_line(self, index, false, suppress_cursor, &rendered)
+        })
     }
 
     fn render_editor_line(&self, task: &Task, index: usize) -> String {
         let indent = task.indent.replace('\t', "    ");
         let prefix = format!("{}{} ", indent, checkbox_symbol(task.completed));
-        let content = format!(
-            "{}{}",
-            prefix,
-            self.text_input
-                .view(&self.input_placeholder, self.editor_width())
-        );
-        format_line(self, index, true, false, &content)
+        let content = self.editor_view(&prefix);
+        format_line(self, index, true, false, visible_width(&prefix), &content)
     }
 
-    fn render_section_line(&self, title: &str, index: usize, suppress_cursor: bool) -> String {
-        let body = format!("\x1b[1m{}\x1b[0m", title);
+    // The text input after `prefix`, either scrolled on one row or wrapped
+    // over several rows depending on `edit_wrap`. format_line indents the
+    // extra rows to line up after the prefix.
+    fn editor_view(&self, prefix: &str) -> String {
+        if !self.edit_wrap {
+            return format!(
+                "{}{}",
+                prefix,
+                self.text_input
+                    .view(&self.input_placeholder, self.editor_width())
+            );
+        }
+        let rows = self
+            .text_input
+            .wrapped_view(&self.input_placeholder, self.editor_width());
+        format!("{}{}", prefix, rows.join("\n"))
+    }
+
+    fn render_section_line(
+        &self,
+        title: &str,
+        collapsed: bool,
+        index: usize,
+        suppress_cursor: bool,
+    ) -> String {
+        let mut body = format!("\x1b[1m{}\x1b[0m", title);
+        if self.search_active() && self.mode != Mode::Edit {
+            body = highlight_matches(&body, self.search_query());
+        }
+        if collapsed {
+            body = format!("▸ {}", body);
+        }
+        let (done, total) = self.section_progress(index);
+        if total > 0 {
+            body.push_str(&format!(
+                " {}{}({}/{}){}",
+                DIM_ON,
+                progress_bar(done, total, self.window_width as usize),
+                done,
+                total,
+                DIM_OFF
+            ));
+        }
         format_section_line(self, index, suppress_cursor, &body)
     }
 
-    fn render_section_editor_line(&self, _index: usize) -> String {
-        format!(
-            "  >{}\n",
-            self.text_input
-                .view(&self.input_placeholder, self.editor_width())
-        )
+    fn render_file_line(&self, path: &Path, index: usize, suppress_cursor: bool) -> String {
+        let name = path
+            .file_name()
+            .and_then(|s| s.to_str())
+            .unwrap_or_default();
+        let body = format!("\x1b[1;4m{}\x1b[0m", name);
+        format_section_line(self, index, suppress_cursor, &body)
     }
 
-    fn render_footer(&self) -> String {
-        let mut completed: usize = 0;
-        let mut total_tasks: usize = 0;
-        for line in &self.lines {
-            if let LineItem::Task(task) = line {
-                total_tasks += 1;
-                if task.completed {
-                    completed += 1;
+    fn render_rule_line(&self, index: usize, suppress_cursor: bool) -> String {
+        let width = self.renderer_width.max(3);
+        let body = format!("{}{}{}", DIM_ON, "─".repeat(width), DIM_OFF);
+        format_section_line(self, index, suppress_cursor, &body)
+    }
+
+    fn render_comment_line(&self, text: &str, index: usize, suppress_cursor: bool) -> String {
+        let body = format!("{}{}{}", DIM_ON, text, DIM_OFF);
+        format_section_line(self, index, suppress_cursor, &body)
+    }
+
+    fn render_section_editor_line(&self, index: usize) -> String {
+        format_line(self, index, true, false, 0, &self.editor_view(""))
+    }
+
+    // Message (and optional quick-start hints) for a file with no tasks.
+    fn empty_state(&self) -> String {
+        let mut out = format!("{}\n", self.config.display.empty_message);
+        if self.config.display.empty_hints {
+            out.push('\n');
+            for hint in EMPTY_HINTS {
+                out.push_str(&format!("  {}{}{}\n", DIM_ON, hint, DIM_OFF));
+            }
+        }
+        out
+    }
+
+    // Footer hints for the kind of line under the cursor.
+    fn normal_hints(&self) -> Vec<&'static str> {
+        match self.lines.get(self.cursor) {
+            None => vec!["o new", "S section", "T today", "I inbox", "q quit"],
+            Some(LineItem::Task(_)) => {
+                let mut hints = vec!["j/k move", "space toggle", "i inline", "e vim", "dd del"];
+                if self.task_block_end(self.cursor) > self.cursor + 1 {
+                    hints.extend(["Tab fold", "A subtasks"]);
                 }
+                hints.extend(["o/O new", "@ due", "/ search", "u undo", "q quit"]);
+                hints
+            }
+            Some(LineItem::Section { collapsed, .. }) => {
+                let fold = if *collapsed {
+                    "Enter expand"
+                } else {
+                    "S-Tab outline"
+                };
+                vec![
+                    "j/k move",
+                    "i rename",
+                    "dd del",
+                    fold,
+                    "o new task",
+                    "S section",
+                    "q quit",
+                ]
+            }
+            Some(LineItem::Comment { .. }) => {
+                vec![
+                    "j/k move",
+                    "+ restore",
+                    "dd del",
+                    "H hide comments",
+                    "q quit",
+                ]
+            }
+            Some(LineItem::File { .. } | LineItem::Rule | LineItem::Raw { .. }) => {
+                vec![
+                    "j/k move",
+                    "dd del",
+                    "o new",
+                    "S section",
+                    "/ search",
+                    "q quit",
+                ]
             }
         }
+    }
+
+    pub(crate) fn render_footer(&self) -> String {
+        self.render_footer_at(None)
+    }
+
+    // Footer with the `[first-last/total]` rows shown when the list scrolls.
+    fn render_footer_at(&self, position: Option<(usize, usize, usize)>) -> String {
+        let (completed, total_tasks) = task_progress(&self.lines);
         let open = total_tasks.saturating_sub(completed);
 
         let mut parts = Vec::new();
@@ -214,20 +455,8 @@ impl App {
                 parts.extend(["Tab/S-Tab indent", "Esc save & exit", "Enter new below"]);
             }
         } else {
-            parts.extend([
-                "j/k move",
-                "space toggle",
-                "dd del",
-                "u undo",
-                "^r redo",
-                "/ search",
-                "e vim",
-                "i inline",
-                "o/O new",
-                "S section",
-                "q quit",
-            ]);
-            if self.selection_active {
+            parts.extend(self.normal_hints());
+            if self.has_selection() {
                 parts.push("Esc cancel selection");
             }
             if self.search_active() && self.mode != Mode::Edit {
@@ -237,6 +466,28 @@ impl App {
 
         let mut status = parts.join(" · ");
         status.push_str(&format!("\n{} open · {} completed", open, completed));
+        if let Some((first, last, total)) = position {
+            status.push_str(&format!(" · [{}-{}/{}]", first, last, total));
+        }
+        if let Some(label) = self.filter.label() {
+            status.push_str(&format!(" · filter: {}", label));
+        }
+        if let Some(tag) = &self.tag_filter {
+            status.push_str(&format!(" · #{}", tag));
+        }
+        if self.has_selection() {
+            status.push_str(&format!(" · {} selected", self.selected_indices().len()));
+        }
+        if !self.undo_stack.is_empty() || !self.redo_stack.is_empty() {
+            status.push_str(&format!(
+                " · undo {}/{}",
+                self.undo_stack.len(),
+                MAX_UNDO_HISTORY
+            ));
+            if !self.redo_stack.is_empty() {
+                status.push_str(&format!(" · redo {}", self.redo_stack.len()));
+            }
+        }
         if !self.status_message.is_empty() {
             status.push_str(&format!(" · {}", self.status_message));
         }
@@ -247,7 +498,12 @@ impl App {
             status.push_str(&format!("\nError: {}", err));
         }
 
-        let search_line = if self.mode == Mode::Search {
+        let search_line = if self.mode == Mode::Command {
+            format!(
+                ":{}",
+                self.command_input.view("line number", self.editor_width())
+            )
+        } else if self.mode == Mode::Search {
             format!("/{}", self.search_input.view("search", self.editor_width()))
         } else if self.search_active() && self.mode != Mode::Edit {
             format!("/{}", self.search_query())
@@ -262,11 +518,17 @@ impl App {
     }
 
     pub fn ensure_renderer_width(&mut self, total_width: u16) {
-        let wrap = (total_width as usize).saturating_sub(WRAP_MARGIN);
+        let mut wrap = (total_width as usize).saturating_sub(WRAP_MARGIN);
+        // Cap the reading width; highlights still clear to the real line end.
+        let max_width = self.config.display.max_width;
+        if max_width > 0 {
+            wrap = wrap.min(max_width);
+        }
         if wrap == self.renderer_width {
             return;
         }
         self.renderer_width = wrap;
+        self.markdown_cache.get_mut().clear();
     }
 
     pub fn editor_width(&self) -> usize {
@@ -274,29 +536,80 @@ impl App {
         width.max(20)
     }
 
-    fn ensure_scroll(&mut self, total_items: usize, visible_items: usize, cursor_pos: usize) {
-        if total_items == 0 {
+    // Move scroll_offset (an item position) just enough that the items from
+    // it through the cursor's fit in `rows` screen rows, given each item's
+    // height. Space left below the last item is filled by scrolling back up.
+    fn ensure_scroll(&mut self, heights: &[usize], rows: usize, cursor_pos: usize) {
+        if heights.is_empty() {
             self.scroll_offset = 0;
             return;
         }
-        let visible = visible_items.max(1);
-        if cursor_pos < self.scroll_offset {
-            self.scroll_offset = cursor_pos;
-        } else if cursor_pos >= self.scroll_offset + visible {
-            self.scroll_offset = cursor_pos + 1 - visible;
+        let cursor_pos = cursor_pos.min(heights.len() - 1);
+        let rows = rows.max(1);
+        self.scroll_offset = self.scroll_offset.min(cursor_pos);
+        while self.scroll_offset < cursor_pos
+            && heights[self.scroll_offset..=cursor_pos]
+                .iter()
+                .sum::<usize>()
+                > rows
+        {
+            self.scroll_offset += 1;
         }
-        if self.scroll_offset > total_items.saturating_sub(1) {
-            self.scroll_offset = total_items.saturating_sub(1);
+        while self.scroll_offset > 0
+            && heights[self.scroll_offset - 1..].iter().sum::<usize>() <= rows
+        {
+            self.scroll_offset -= 1;
         }
     }
 }
 
-pub fn render_header(path: &Path) -> String {
+// A bar like `███░░░░░ ` sized to a tenth of the terminal width (at most
+// 20 cells); empty on terminals too narrow to spare the room.
+fn progress_bar(done: usize, total: usize, window_width: usize) -> String {
+    let width = (window_width / 10).min(SECTION_BAR_MAX);
+    if width < SECTION_BAR_MIN || total == 0 {
+        return String::new();
+    }
+    let filled = done * width / total;
+    format!("{}{} ", "█".repeat(filled), "░".repeat(width - filled))
+}
+
+// Highlight `!1` in red and `!2` in yellow.
+fn decorate_priority(body: &str) -> String {
+    map_priority(body, |token, level| {
+        match PRIORITY_COLORS.get(level as usize - 1) {
+            Some((on, off)) => format!("{}{}{}", on, token, off),
+            None => token.to_string(),
+        }
+    })
+}
+
+// `progress` is (completed, total); it's left out while there are no tasks.
+pub fn render_header(path: &Path, overdue: usize, progress: Option<(usize, usize)>) -> String {
     let name = path
         .file_name()
         .and_then(|s| s.to_str())
         .unwrap_or("todo.md");
-    format!("Managing {}\n\n", name)
+    let mut badge = match progress {
+        Some((done, total)) if total > 0 => {
+            format!(" — {}% ({}/{})", done * 100 / total, done, total)
+        }
+        _ => String::new(),
+    };
+    if overdue > 0 {
+        badge.push_str(&format!("  {}⚠ {} overdue{}", RED_ON, overdue, RED_OFF));
+    }
+    if path.is_dir() {
+        return format!("Managing {}/ (board){}\n\n", name, badge);
+    }
+    format!("Managing {}{}\n\n", name, badge)
+}
+
+// Columns a task's `[ ]` occupies on its first row, after the gutter and
+// indentation.
+pub(crate) fn checkbox_columns(task: &Task) -> std::ops::Range<usize> {
+    let start = visible_width(gutter(false)) + task.indent.replace('\t', "    ").len();
+    start..start + visible_width(checkbox_symbol(task.completed))
 }
 
 fn checkbox_symbol(done: bool) -> &'static str {
@@ -307,46 +620,45 @@ fn checkbox_symbol(done: bool) -> &'static str {
     }
 }
 
+// Cursor column shared by every line kind, so the `>` never shifts as it
+// moves between sections, tasks and editors.
+fn gutter(cursor: bool) -> &'static str {
+    if cursor {
+        ">  "
+    } else {
+        "   "
+    }
+}
+
+// Lay out a possibly wrapped line after the cursor gutter. Rows after the
+// first are indented by `hang` more columns, so wrapped text lines up under
+// the text rather than under the checkbox.
 fn format_line(
     app: &App,
     index: usize,
     editing: bool,
     suppress_cursor: bool,
+    hang: usize,
     body: &str,
 ) -> String {
-    let cursor_char = if editing || (!suppress_cursor && index == app.cursor) {
-        ">"
-    } else {
-        " "
-    };
+    let prefix = gutter(editing || (!suppress_cursor && index == app.cursor));
+    let cont_prefix = format!("{}{}", gutter(false), " ".repeat(hang));
+    let cont_prefix = cont_prefix.as_str();
     let is_selected = !editing && app.is_selected(index);
     let lines: Vec<&str> = body.split('\n').collect();
 
-    let prefix = format!("{}  ", cursor_char);
-    let cont_prefix = "   ";
     let mut out = String::new();
     for (i, line) in lines.iter().enumerate() {
         if i == 0 {
             if is_selected {
-                // Strip ANSI so the highlight background is uniform.
-                out.push_str(HIGHLIGHT_ON);
-                out.push_str(&prefix);
-                out.push_str(&strip_ansi(line));
-                out.push_str(CLEAR_TO_EOL);
-                out.push_str(HIGHLIGHT_OFF);
-                out.push('\n');
+                out.push_str(&highlight_row(app, prefix, line));
             } else {
-                out.push_str(&prefix);
+                out.push_str(prefix);
                 out.push_str(line);
                 out.push('\n');
             }
         } else if is_selected {
-            out.push_str(HIGHLIGHT_ON);
-            out.push_str(cont_prefix);
-            out.push_str(&strip_ansi(line));
-            out.push_str(CLEAR_TO_EOL);
-            out.push_str(HIGHLIGHT_OFF);
-            out.push('\n');
+            out.push_str(&highlight_row(app, cont_prefix, line));
         } else {
             out.push_str(cont_prefix);
             out.push_str(line);
@@ -356,30 +668,61 @@ fn format_line(
     out
 }
 
-fn format_section_line(app: &App, index: usize, suppress_cursor: bool, body: &str) -> String {
-    let cursor_char = if !suppress_cursor && index == app.cursor {
-        ">"
+// One selected row, cut or padded to exactly the window width so the
+// highlight ends at the screen edge: a row that is too long (an unwrapped
+// header after the terminal shrank) would otherwise spill its background
+// onto the next screen row. ANSI is stripped so the background is uniform.
+// Before the terminal size is known the width is 0 and the row is left as is.
+fn highlight_row(app: &App, prefix: &str, line: &str) -> String {
+    let width = app.window_width as usize;
+    let row = format!("{}{}", prefix, strip_ansi(line));
+    let row = if width == 0 {
+        row
     } else {
-        " "
+        truncate_visible(&row, width)
     };
+    let pad = width.saturating_sub(visible_width(&row));
+    format!(
+        "{}{}{}{}\n",
+        HIGHLIGHT_ON,
+        row,
+        " ".repeat(pad),
+        HIGHLIGHT_OFF
+    )
+}
+
+fn format_section_line(app: &App, index: usize, suppress_cursor: bool, body: &str) -> String {
+    let prefix = gutter(!suppress_cursor && index == app.cursor);
     let is_selected = app.is_selected(index);
-    let prefix = format!("{}  ", cursor_char);
     if is_selected {
-        format!(
-            "{}{}{}{}{}\n",
-            HIGHLIGHT_ON,
-            prefix,
-            strip_ansi(body),
-            CLEAR_TO_EOL,
-            HIGHLIGHT_OFF
-        )
+        highlight_row(app, prefix, body)
     } else {
         format!("{}{}\n", prefix, body)
     }
 }
 
-fn strip_ansi(input: &str) -> String {
-    ANSI_ESCAPE_RE.replace_all(input, "").to_string()
+// Byte ranges of `text` matching `query` case-insensitively, with full
+// Unicode lowercasing (so `É` matches `é`). Search filtering and the match
+// highlight both use this, so they agree on what matches.
+pub(crate) fn match_ranges(text: &str, query: &str) -> Vec<(usize, usize)> {
+    let query: String = query.chars().flat_map(char::to_lowercase).collect();
+    if query.is_empty() {
+        return Vec::new();
+    }
+    // Lowercase char by char, remembering where each folded byte came from:
+    // lowercasing can change a character's length in bytes.
+    let mut folded = String::with_capacity(text.len());
+    let mut origin = Vec::with_capacity(text.len());
+    for (start, ch) in text.char_indices() {
+        for lower in ch.to_lowercase() {
+            folded.push(lower);
+            origin.resize(folded.len(), (start, start + ch.len_utf8()));
+        }
+    }
+    folded
+        .match_indices(&query)
+        .map(|(start, _)| (origin[start].0, origin[start + query.len() - 1].1))
+        .collect()
 }
 
 fn highlight_matches(rendered: &str, query: &str) -> String {
@@ -392,10 +735,7 @@ fn highlight_matches(rendered: &str, query: &str) -> String {
         return rendered.to_string();
     }
 
-    let ranges: Vec<(usize, usize)> = plain
-        .match_indices(query)
-        .map(|(start, _)| (start, start + query.len()))
-        .collect();
+    let ranges = match_ranges(&plain, query);
     if ranges.is_empty() {
         return rendered.to_string();
     }
@@ -475,3 +815,109 @@ fn count_lines(input: &str) -> usize {
     }
     lines
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::app::tests::app_with;
+
+    fn task_rows(app: &App, index: usize) -> Vec<String> {
+        let LineItem::Task(task) = &app.lines[index] else {
+            panic!("line {} is not a task", index);
+        };
+        app.render_task_line(task, index, false)
+            .lines()
+            .map(strip_ansi)
+            .collect()
+    }
+
+    // Columns before the first non-space character.
+    fn lead(row: &str) -> usize {
+        visible_width(row) - visible_width(row.trim_start())
+    }
+
+    #[test]
+    fn inline_code_continuation_prefix_matches_text_column() {
+        let (_dir, mut app) = app_with(
+            "## A\n- [ ] run `cargo build --release` and then `cargo test --all` before pushing\n",
+        );
+        app.window_width = 50;
+        app.ensure_renderer_width(50);
+        let rows = task_rows(&app, 1);
+        assert!(rows.len() > 1, "expected a wrapped task: {:?}", rows);
+        let text_column = visible_width(gutter(false)) + visible_width("[ ] ");
+        for row in &rows[1..] {
+            assert_eq!(lead(row), text_column, "row {:?}", row);
+        }
+        for row in &rows {
+            assert!(visible_width(row) <= 50, "row too wide: {:?}", row);
+        }
+    }
+
+    #[test]
+    fn gutter_width_is_the_same_for_every_line_kind() {
+        let (_dir, mut app) = app_with("## Errands\n- [ ] task\nsome notes\n---\n");
+        app.window_width = 80;
+        app.window_height = 40;
+        app.ensure_renderer_width(80);
+        let width = visible_width(gutter(false));
+        assert_eq!(visible_width(gutter(true)), width);
+        for cursor in 0..app.lines.len() {
+            app.cursor = cursor;
+            let view = strip_ansi(&app.render());
+            for body in ["Errands", "[ ] task", "some notes", "───"] {
+                let row = view
+                    .lines()
+                    .find(|row| row.contains(body))
+                    .unwrap_or_else(|| panic!("no row for {:?}", body));
+                let column = visible_width(&row[..row.find(body).unwrap()]);
+                assert_eq!(column, width, "{:?} with the cursor on {}", row, cursor);
+            }
+        }
+    }
+
+    #[test]
+    fn wrapped_rows_hang_under_the_text() {
+        let long = "word ".repeat(20);
+        let (_dir, mut app) = app_with(&format!(
+            "## A\n- [ ] {}\n  - [ ] {}\n",
+            long.trim(),
+            long.trim()
+        ));
+        app.window_width = 40;
+        app.ensure_renderer_width(40);
+        // The parent has a fold marker, the child an indent.
+        for (index, before_text) in [(1, "[ ] ▾ "), (2, "  [ ] ")] {
+            let rows = task_rows(&app, index);
+            assert!(rows.len() > 1, "expected a wrapped task: {:?}", rows);
+            let text_column = visible_width(gutter(false)) + visible_width(before_text);
+            let first = &rows[0];
+            assert_eq!(
+                visible_width(&first[..first.find("word").unwrap()]),
+                text_column
+            );
+            for row in &rows[1..] {
+                assert_eq!(lead(row), text_column, "row {:?}", row);
+            }
+        }
+    }
+
+    #[test]
+    fn match_ranges_fold_unicode_case() {
+        let text = "Café ÉCOLE école";
+        let ranges = match_ranges(text, "ÉCOLE");
+        let found: Vec<&str> = ranges.iter().map(|&(s, e)| &text[s..e]).collect();
+        assert_eq!(found, vec!["ÉCOLE", "école"]);
+        // `İ` lowercases to two chars; the range still covers whole chars.
+        let text = "İstanbul";
+        let ranges = match_ranges(text, "stan");
+        assert_eq!(&text[ranges[0].0..ranges[0].1], "stan");
+        assert!(match_ranges("abc", "").is_empty());
+    }
+
+    #[test]
+    fn highlight_follows_unicode_matches() {
+        let highlighted = highlight_matches("ÉCOLE", "école");
+        assert_eq!(highlighted, format!("{}ÉCOLE{}", MATCH_ON, MATCH_OFF));
+    }
+}
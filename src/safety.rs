@@ -0,0 +1,48 @@
+use log::debug;
+
+use crate::io::{pending_data_loss, write_backup};
+use crate::model::App;
+
+impl App {
+    // Before a save drops or reformats lines the parser doesn't keep as
+    // written (odd spacing around checkboxes and headers, ...), back the file
+    // up and describe what changed; `--logs` records the lines before and
+    // after. An error means the backup failed and the save should not go
+    // ahead.
+    pub(crate) fn backup_before_data_loss(&self) -> Result<Option<String>, String> {
+        if !self.config.save.check_data_loss {
+            return Ok(None);
+        }
+        let losses = pending_data_loss(&self.file_path, &self.lines);
+        if losses.is_empty() {
+            return Ok(None);
+        }
+
+        let mut dropped = 0;
+        let mut backups = Vec::new();
+        for loss in &losses {
+            debug!("save rewrites lines in {}:", loss.path.display());
+            for line in &loss.lines {
+                debug!("- {}", line);
+            }
+            for line in &loss.rewritten {
+                debug!("+ {}", line);
+            }
+            let backup = write_backup(&loss.path).map_err(|e| {
+                format!("not saved: backup of {} failed: {}", loss.path.display(), e)
+            })?;
+            dropped += loss.lines.len();
+            backups.push(
+                backup
+                    .file_name()
+                    .map(|name| name.to_string_lossy().into_owned())
+                    .unwrap_or_default(),
+            );
+        }
+        Ok(Some(format!(
+            "⚠ {} lines rewritten, original kept in {}",
+            dropped,
+            backups.join(", ")
+        )))
+    }
+}
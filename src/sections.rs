@@ -0,0 +1,177 @@
+use crate::edit::get_indent_level;
+use crate::model::{App, LineItem};
+use crate::yank::reindent_block;
+
+impl App {
+    // Indices of section headers, in order; `>N` addresses them 1-based.
+    pub(crate) fn section_indices(&self) -> Vec<usize> {
+        self.lines
+            .iter()
+            .enumerate()
+            .filter(|(_, line)| line.is_section())
+            .map(|(idx, _)| idx)
+            .collect()
+    }
+
+    // One past the last line of the task at `index` and its nested subtasks.
+    // Comment lines between subtasks (commented-out children) stay inside
+    // the block; trailing ones after the last subtask do not.
+    pub(crate) fn task_block_end(&self, index: usize) -> usize {
+        let Some(LineItem::Task(parent)) = self.lines.get(index) else {
+            return index + 1;
+        };
+        let parent_level = get_indent_level(&parent.indent, &self.indent_unit);
+        let mut end = index + 1;
+        for (offset, line) in self.lines[index + 1..].iter().enumerate() {
+            match line {
+                LineItem::Comment { .. } => {}
+                LineItem::Task(task)
+                    if get_indent_level(&task.indent, &self.indent_unit) > parent_level =>
+                {
+                    end = index + 2 + offset;
+                }
+                _ => break,
+            }
+        }
+        end
+    }
+
+    // Move to the next (`forward`) or previous visible section header.
+    pub fn jump_to_section(&mut self, forward: bool) {
+        let mut headers = self
+            .visible_indices()
+            .into_iter()
+            .filter(|&i| self.lines[i].is_section() || self.lines[i].is_file());
+        let target = if forward {
+            headers.find(|&i| i > self.cursor)
+        } else {
+            headers.rfind(|&i| i < self.cursor)
+        };
+        match target {
+            Some(idx) => {
+                self.clear_selection();
+                self.cursor = idx;
+            }
+            None => self.set_status("No more sections"),
+        }
+    }
+
+    // Prompt shown after `>`: the numbered sections a task can be sent to.
+    pub fn section_move_prompt(&self) -> String {
+        let names: Vec<String> = self
+            .section_indices()
+            .iter()
+            .take(9)
+            .enumerate()
+            .filter_map(|(n, &idx)| match &self.lines[idx] {
+                LineItem::Section { title, .. } => Some(format!("{} {}", n + 1, title)),
+                _ => None,
+            })
+            .collect();
+        if names.is_empty() {
+            "No sections".to_string()
+        } else {
+            format!("Move to: {}", names.join(" · "))
+        }
+    }
+
+    // Move the current task (with its subtasks) to the end of section `number`.
+    pub fn move_task_to_section(&mut self, number: usize) {
+        if !matches!(self.lines.get(self.cursor), Some(LineItem::Task(_))) {
+            self.set_status("No task to move");
+            return;
+        }
+        let sections = self.section_indices();
+        let Some(&header) = number.checked_sub(1).and_then(|n| sections.get(n)) else {
+            self.set_status(format!("No section {}", number));
+            return;
+        };
+        let LineItem::Section { title, .. } = &self.lines[header] else {
+            return;
+        };
+        let title = title.clone();
+
+        self.save_undo_state();
+        self.clear_selection();
+        self.relocate_task_to_section(header);
+        self.save_and_set_status(&format!("Moved to {}", title));
+    }
+
+    // Toggle the current task and move it to the configured to-do or done
+    // section to match its new state, as one undoable step.
+    pub fn toggle_and_file_task(&mut self) {
+        let completed = match self.lines.get(self.cursor) {
+            Some(LineItem::Task(task)) => !task.completed,
+            _ => {
+                self.set_status("No task to toggle");
+                return;
+            }
+        };
+        let target = if completed {
+            self.config.lists.done.clone()
+        } else {
+            self.config.lists.todo.clone()
+        };
+        let Some(header) = self
+            .lines
+            .iter()
+            .position(|line| matches!(line, LineItem::Section { title, .. } if title.eq_ignore_ascii_case(&target)))
+        else {
+            self.set_status(format!("No \"{}\" section", target));
+            return;
+        };
+
+        self.save_undo_state();
+        self.clear_selection();
+        let mark = self.config.format.done_mark;
+        let stamp = self.done_stamp();
+        if let Some(LineItem::Task(task)) = self.lines.get_mut(self.cursor) {
+            task.set_completed(completed, mark, stamp.as_deref());
+        }
+        let state = if completed { "Completed" } else { "Reopened" };
+        if self.enclosing_header(self.cursor) == Some(header) {
+            self.remember_edit(self.cursor);
+            self.save_and_set_status(state);
+        } else {
+            self.relocate_task_to_section(header);
+            self.remember_edit(self.cursor);
+            self.save_and_set_status(&format!("{}, moved to {}", state, target));
+        }
+    }
+
+    // One past the last line belonging to the section whose header is at
+    // `header`. Blank lines separating it from the next section don't count,
+    // so tasks appended here land above them.
+    pub(crate) fn section_end(&self, header: usize) -> usize {
+        let mut end = self.lines[header + 1..]
+            .iter()
+            .position(|line| line.is_section() || line.is_file())
+            .map_or(self.lines.len(), |offset| header + 1 + offset);
+        while end > header + 1 && self.lines[end - 1].is_blank() {
+            end -= 1;
+        }
+        end
+    }
+
+    // Move the task at the cursor (with its subtasks) to the end of the
+    // section at `header`, unindented to the top level. The caller handles
+    // undo and saving.
+    pub(crate) fn relocate_task_to_section(&mut self, header: usize) {
+        let start = self.cursor;
+        let end = self.task_block_end(start);
+        let mut block: Vec<LineItem> = self.lines.drain(start..end).collect();
+        reindent_block(&mut block, 0, &self.indent_unit);
+
+        let header = if header > start {
+            header - block.len()
+        } else {
+            header
+        };
+        let insert_at = self.section_end(header);
+        self.lines.splice(insert_at..insert_at, block);
+        if let LineItem::Section { collapsed, .. } = &mut self.lines[header] {
+            *collapsed = false;
+        }
+        self.cursor = insert_at;
+    }
+}
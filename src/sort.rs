@@ -0,0 +1,132 @@
+use std::cmp::Ordering;
+
+use crate::config::SortKey;
+use crate::edit::indent_width;
+use crate::metadata::due;
+use crate::model::{App, LineItem, Task};
+
+impl App {
+    // Reapply the file's configured sort order, keeping the cursor on its line.
+    pub(crate) fn apply_sort_preference(&mut self) {
+        let Some(keys) = self.config.sort_for(&self.file_path) else {
+            return;
+        };
+        let order = sorted_order(&self.lines, keys);
+        if order.iter().enumerate().all(|(i, &old)| i == old) {
+            return;
+        }
+
+        let lines = order.iter().map(|&old| self.lines[old].clone()).collect();
+        self.lines = lines;
+        if let Some(pos) = order.iter().position(|&old| old == self.cursor) {
+            self.cursor = pos;
+        }
+    }
+
+    // Stably sort the tasks in the cursor's section by `key`: `s` by
+    // priority (`!1` first, unprioritized last), `D` open before done.
+    // Subtasks move with their parent; the section's header and other lines
+    // stay where they are. Goes through apply_bulk, so it can be previewed.
+    pub fn sort_section(&mut self, key: SortKey) {
+        let (start, end) = match self.enclosing_header(self.cursor) {
+            Some(header) => (header + 1, self.section_end(header)),
+            None => (
+                0,
+                self.lines
+                    .iter()
+                    .position(|line| line.is_section() || line.is_file())
+                    .unwrap_or(self.lines.len()),
+            ),
+        };
+        let order: Vec<usize> = sorted_order(&self.lines[start..end], &[key])
+            .into_iter()
+            .map(|i| start + i)
+            .collect();
+        let by = match key {
+            SortKey::Incomplete => "open first",
+            SortKey::Priority => "by priority",
+            SortKey::Due => "by due date",
+        };
+        if order.iter().enumerate().all(|(i, &old)| start + i == old) {
+            self.set_status(format!("Already sorted {}", by));
+            return;
+        }
+
+        let mut lines = self.lines.clone();
+        let sorted: Vec<LineItem> = order.iter().map(|&old| self.lines[old].clone()).collect();
+        lines.splice(start..end, sorted);
+        let cursor = match order.iter().position(|&old| old == self.cursor) {
+            Some(pos) => start + pos,
+            None => self.cursor,
+        };
+        self.apply_bulk_at(lines, &format!("Sorted {}", by), cursor);
+    }
+}
+
+// Stable ordering of `lines` by `keys`, returned as old indices in new order.
+// Sections stay put; within each section, top-level tasks are sorted together
+// with the nested tasks beneath them.
+pub fn sorted_order(lines: &[LineItem], keys: &[SortKey]) -> Vec<usize> {
+    let mut order = Vec::with_capacity(lines.len());
+    let mut start = 0;
+    while start < lines.len() {
+        if !lines[start].is_task() {
+            order.push(start);
+            start += 1;
+            continue;
+        }
+        let end = (start..lines.len())
+            .find(|&i| !lines[i].is_task())
+            .unwrap_or(lines.len());
+        order.extend(sort_run(lines, start, end, keys));
+        start = end;
+    }
+    order
+}
+
+// Sort a contiguous run of tasks as blocks headed by its shallowest tasks.
+fn sort_run(lines: &[LineItem], start: usize, end: usize, keys: &[SortKey]) -> Vec<usize> {
+    let level = |i: usize| match &lines[i] {
+        LineItem::Task(task) => indent_width(&task.indent),
+        _ => 0,
+    };
+    let base = (start..end).map(level).min().unwrap_or(0);
+
+    let mut blocks: Vec<Vec<usize>> = Vec::new();
+    for i in start..end {
+        match blocks.last_mut() {
+            Some(block) if level(i) > base => block.push(i),
+            _ => blocks.push(vec![i]),
+        }
+    }
+
+    blocks.sort_by(|a, b| match (&lines[a[0]], &lines[b[0]]) {
+        (LineItem::Task(a), LineItem::Task(b)) => compare_tasks(a, b, keys),
+        _ => Ordering::Equal,
+    });
+    blocks.into_iter().flatten().collect()
+}
+
+fn compare_tasks(a: &Task, b: &Task, keys: &[SortKey]) -> Ordering {
+    for key in keys {
+        let ordering = match key {
+            SortKey::Incomplete => a.completed.cmp(&b.completed),
+            // Tasks without a priority sort after every prioritized task.
+            SortKey::Priority => a
+                .priority()
+                .unwrap_or(u8::MAX)
+                .cmp(&b.priority().unwrap_or(u8::MAX)),
+            // Earliest `@due` first; undated (or malformed) dates go last.
+            SortKey::Due => match (due(&a.text), due(&b.text)) {
+                (Some(a), Some(b)) => a.cmp(&b),
+                (Some(_), None) => Ordering::Less,
+                (None, Some(_)) => Ordering::Greater,
+                (None, None) => Ordering::Equal,
+            },
+        };
+        if ordering != Ordering::Equal {
+            return ordering;
+        }
+    }
+    Ordering::Equal
+}
@@ -0,0 +1,134 @@
+use crate::ansi::{truncate_visible, visible_width};
+use crate::markdown::plain_text;
+use crate::model::{App, LineItem};
+
+const PANE_GAP: &str = " │ ";
+
+impl App {
+    // Switch between the single list and open | done columns.
+    pub fn toggle_split_view(&mut self) {
+        self.split_view = !self.split_view;
+        self.clear_selection();
+        if self.split_view {
+            let pane = self.pane_indices(self.cursor_in_done_pane());
+            self.ensure_cursor_visible_in(&pane);
+            self.set_status("Split view");
+        } else {
+            self.set_status("Single-column view");
+        }
+    }
+
+    // Visible tasks in the open or the done pane.
+    pub(crate) fn pane_indices(&self, completed: bool) -> Vec<usize> {
+        self.visible_indices()
+            .into_iter()
+            .filter(
+                |&i| matches!(&self.lines[i], LineItem::Task(task) if task.completed == completed),
+            )
+            .collect()
+    }
+
+    pub(crate) fn cursor_in_done_pane(&self) -> bool {
+        matches!(self.lines.get(self.cursor), Some(LineItem::Task(task)) if task.completed)
+    }
+
+    // Move to the open (`done` false) or done pane, landing on the task
+    // nearest the cursor's line.
+    pub fn focus_pane(&mut self, done: bool) {
+        let pane = self.pane_indices(done);
+        let nearest = pane
+            .iter()
+            .min_by_key(|&&i| i.abs_diff(self.cursor))
+            .copied();
+        match nearest {
+            Some(idx) => self.cursor = idx,
+            None if done => self.set_status("No done tasks"),
+            None => self.set_status("No open tasks"),
+        }
+    }
+
+    pub(crate) fn render_split(&mut self) -> String {
+        let header = self.header();
+        self.clamp_cursor_to_visible();
+        if !self.lines.get(self.cursor).is_some_and(LineItem::is_task) {
+            let done = self.pane_indices(false).is_empty();
+            self.focus_pane(done);
+        }
+        let footer = self.render_footer();
+        let done = self.cursor_in_done_pane();
+        let left = self.pane_indices(false);
+        let right = self.pane_indices(true);
+        let active = if done { &right } else { &left };
+
+        let width = (self.window_width as usize).saturating_sub(visible_width(PANE_GAP)) / 2;
+        let rows = if self.window_height == 0 {
+            left.len().max(right.len())
+        } else {
+            (self.window_height as usize)
+                .saturating_sub(header.matches('\n').count() + footer.matches('\n').count() + 2)
+                .max(1)
+        };
+
+        // Scroll the active pane so the cursor stays in view; the other
+        // pane shows from the top.
+        let pos = active.iter().position(|&i| i == self.cursor).unwrap_or(0);
+        if pos < self.scroll_offset {
+            self.scroll_offset = pos;
+        } else if pos >= self.scroll_offset + rows {
+            self.scroll_offset = pos + 1 - rows;
+        }
+        let (left_start, right_start) = if done {
+            (0, self.scroll_offset)
+        } else {
+            (self.scroll_offset, 0)
+        };
+
+        let mut out = header;
+        let titles = (
+            format!("\x1b[1mOpen ({})\x1b[0m", left.len()),
+            format!("\x1b[1mDone ({})\x1b[0m", right.len()),
+        );
+        out.push_str(&format!(
+            "{}{}{}\n",
+            pad(&titles.0, width),
+            PANE_GAP,
+            titles.1
+        ));
+        for row in 0..rows {
+            let cell = |pane: &[usize], start: usize| {
+                pane.get(start + row)
+                    .map(|&idx| self.pane_cell(idx, width))
+                    .unwrap_or_default()
+            };
+            let (l, r) = (cell(&left, left_start), cell(&right, right_start));
+            if l.is_empty() && r.is_empty() {
+                break;
+            }
+            out.push_str(&format!("{}{}{}\n", pad(&l, width), PANE_GAP, r));
+        }
+        out.push('\n');
+        out.push_str(&footer);
+        out
+    }
+
+    fn pane_cell(&self, idx: usize, width: usize) -> String {
+        let LineItem::Task(task) = &self.lines[idx] else {
+            return String::new();
+        };
+        let cursor = if idx == self.cursor { ">" } else { " " };
+        let mark = if task.completed { "[x]" } else { "[ ]" };
+        let line = format!(
+            "{} {}{} {}",
+            cursor,
+            task.indent.replace('\t', "    "),
+            mark,
+            plain_text(&task.text)
+        );
+        truncate_visible(&line, width)
+    }
+}
+
+fn pad(cell: &str, width: usize) -> String {
+    let fill = width.saturating_sub(visible_width(cell));
+    format!("{}{}", cell, " ".repeat(fill))
+}
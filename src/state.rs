@@ -0,0 +1,121 @@
+use std::env;
+use std::fs;
+use std::io;
+use std::path::{Path, PathBuf};
+
+// Small per-user state files under $XDG_STATE_HOME/lazytodo (or
+// ~/.local/state/lazytodo). Each line is `<absolute file path>\t<fields...>`.
+
+const FOLDS_FILE: &str = "folds";
+const CURSOR_FILE: &str = "cursor";
+
+fn state_dir() -> Option<PathBuf> {
+    if let Some(dir) = env::var_os("XDG_STATE_HOME").filter(|d| !d.is_empty()) {
+        return Some(PathBuf::from(dir).join("lazytodo"));
+    }
+    env::var_os("HOME").map(|home| {
+        PathBuf::from(home)
+            .join(".local")
+            .join("state")
+            .join("lazytodo")
+    })
+}
+
+fn path_key(path: &Path) -> String {
+    fs::canonicalize(path)
+        .unwrap_or_else(|_| path.to_path_buf())
+        .display()
+        .to_string()
+}
+
+// Fields of every entry recorded for `path` in the named state file.
+fn read_entries(name: &str, path: &Path) -> Vec<Vec<String>> {
+    let Some(file) = state_dir().map(|dir| dir.join(name)) else {
+        return Vec::new();
+    };
+    let Ok(data) = fs::read_to_string(file) else {
+        return Vec::new();
+    };
+    let key = path_key(path);
+    data.lines()
+        .filter_map(|line| {
+            let mut fields = line.split('\t');
+            (fields.next() == Some(key.as_str())).then(|| fields.map(str::to_string).collect())
+        })
+        .collect()
+}
+
+// Replace every entry for `path` in the named state file with `entries`.
+fn write_entries(name: &str, path: &Path, entries: &[Vec<String>]) -> io::Result<()> {
+    let Some(dir) = state_dir() else {
+        return Ok(());
+    };
+    let file = dir.join(name);
+    let key = path_key(path);
+    let existing = fs::read_to_string(&file).unwrap_or_default();
+
+    let mut out = String::new();
+    for line in existing.lines() {
+        if line.split('\t').next() != Some(key.as_str()) {
+            out.push_str(line);
+            out.push('\n');
+        }
+    }
+    for fields in entries {
+        out.push_str(&key);
+        for field in fields {
+            out.push('\t');
+            out.push_str(&field.replace(['\t', '\n'], " "));
+        }
+        out.push('\n');
+    }
+
+    fs::create_dir_all(&dir)?;
+    fs::write(file, out)
+}
+
+// A folded line remembered across sessions: a section header or a task
+// whose subtasks are hidden, located by its text and line index.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct SavedFold {
+    pub task: bool,
+    pub index: usize,
+    pub text: String,
+}
+
+pub fn load_folds(path: &Path) -> Vec<SavedFold> {
+    read_entries(FOLDS_FILE, path)
+        .into_iter()
+        .filter_map(|fields| match fields.as_slice() {
+            [kind, index, text] if kind == "section" || kind == "task" => Some(SavedFold {
+                task: kind == "task",
+                index: index.parse().ok()?,
+                text: text.clone(),
+            }),
+            _ => None,
+        })
+        .collect()
+}
+
+pub fn save_folds(path: &Path, folds: &[SavedFold]) -> io::Result<()> {
+    let entries: Vec<Vec<String>> = folds
+        .iter()
+        .map(|fold| {
+            let kind = if fold.task { "task" } else { "section" };
+            vec![kind.to_string(), fold.index.to_string(), fold.text.clone()]
+        })
+        .collect();
+    write_entries(FOLDS_FILE, path, &entries)
+}
+
+// Line index the cursor was on when the file was last closed.
+pub fn load_cursor(path: &Path) -> Option<usize> {
+    read_entries(CURSOR_FILE, path)
+        .first()
+        .and_then(|fields| fields.first())
+        .and_then(|index| index.parse().ok())
+}
+
+pub fn save_cursor(path: &Path, cursor: usize) -> io::Result<()> {
+    write_entries(CURSOR_FILE, path, &[vec![cursor.to_string()]])
+}
@@ -0,0 +1,156 @@
+use std::path::{Path, PathBuf};
+
+use log::debug;
+
+use crate::app::default_task_template;
+use crate::edit::clamp_cursor;
+use crate::fold::apply_saved_folds;
+use crate::io::{detect_indent_unit, line_ending_of, load_path, matches_disk, modified_time};
+use crate::model::{App, BufferState};
+use crate::state::{load_cursor, load_folds, save_cursor};
+
+impl App {
+    // Open `paths` as extra tabs after the file the app was created with.
+    pub fn open_tabs(&mut self, paths: Vec<PathBuf>) -> Result<(), String> {
+        if paths.is_empty() {
+            return Ok(());
+        }
+        self.tabs = vec![None];
+        for path in paths {
+            let buffer = load_buffer(path, self.config.format.indent_unit())?;
+            self.tabs.push(Some(buffer));
+        }
+        Ok(())
+    }
+
+    // `gt`/`gT`: move `delta` tabs along, wrapping at either end.
+    pub(crate) fn cycle_tab(&mut self, delta: isize) {
+        let len = self.tabs.len() as isize;
+        if len < 2 {
+            self.set_status("Only one file open");
+            return;
+        }
+        let target = (self.active_tab as isize + delta).rem_euclid(len);
+        self.switch_tab(target as usize);
+    }
+
+    // `{n}gt`: jump to tab `n`, counting from 1.
+    pub(crate) fn switch_tab(&mut self, target: usize) {
+        if self.tabs.len() < 2 {
+            self.set_status("Only one file open");
+            return;
+        }
+        if self.inbox_active {
+            self.set_status("Leave the inbox (I) before switching files");
+            return;
+        }
+        if target == self.active_tab {
+            return;
+        }
+        let Some(mut next) = self.tabs.get_mut(target).and_then(Option::take) else {
+            self.set_status(format!("No tab {}", target + 1));
+            return;
+        };
+
+        self.save_fold_state();
+        if let Err(err) = save_cursor(&self.file_path, self.cursor) {
+            debug!("failed to save cursor: {}", err);
+        }
+        self.swap_buffer(&mut next);
+        self.tabs[self.active_tab] = Some(next);
+        self.active_tab = target;
+
+        // The inbox sits next to the file, so a loaded one belongs to the old tab.
+        self.stashed_buffer = None;
+        self.pending_reload = false;
+        self.clear_selection();
+        self.search_input.reset();
+        self.edit_template = default_task_template(&self.lines);
+        self.clamp_cursor_to_visible();
+        self.error = None;
+        self.set_status(format!(
+            "{} ({}/{})",
+            tab_name(&self.file_path),
+            target + 1,
+            self.tabs.len()
+        ));
+    }
+
+    // Reload background tabs whose files changed on disk. They can't be
+    // mid-edit, but a tab holding unsaved changes is left alone.
+    pub(crate) fn check_background_tabs(&mut self) {
+        for buffer in self.tabs.iter_mut().flatten() {
+            let Ok(mod_time) = modified_time(&buffer.file_path) else {
+                continue;
+            };
+            if mod_time == buffer.last_modified || buffer.dirty {
+                continue;
+            }
+            if matches_disk(&buffer.file_path, &buffer.lines) {
+                buffer.last_modified = mod_time;
+                continue;
+            }
+            match load_path(&buffer.file_path) {
+                Ok((lines, mod_time)) => {
+                    buffer.cursor = clamp_cursor(buffer.cursor, lines.len());
+                    buffer.lines = lines;
+                    buffer.last_modified = mod_time;
+                    buffer.line_ending = line_ending_of(&buffer.file_path);
+                }
+                Err(err) => debug!("failed to reload {}: {}", buffer.file_path.display(), err),
+            }
+        }
+    }
+
+    // One row naming every open file, the active one in reverse video.
+    // Empty when only one file is open.
+    pub(crate) fn tab_bar(&self) -> String {
+        if self.tabs.len() < 2 {
+            return String::new();
+        }
+        let names: Vec<String> = self
+            .tabs
+            .iter()
+            .enumerate()
+            .map(|(i, tab)| {
+                let path = tab.as_ref().map_or(&self.file_path, |b| &b.file_path);
+                let label = format!(" {} {} ", i + 1, tab_name(path));
+                if i == self.active_tab {
+                    format!("\x1b[7m{}\x1b[0m", label)
+                } else {
+                    label
+                }
+            })
+            .collect();
+        format!("{}\n", names.join(""))
+    }
+}
+
+// `default_unit` is the indent used when the file doesn't nest anything yet.
+fn load_buffer(path: PathBuf, default_unit: String) -> Result<BufferState, String> {
+    let (mut lines, mod_time) =
+        load_path(&path).map_err(|e| format!("{}: {}", path.display(), e))?;
+    let indent_unit = detect_indent_unit(&lines).unwrap_or(default_unit);
+    let line_ending = line_ending_of(&path);
+    apply_saved_folds(&mut lines, &load_folds(&path));
+    let cursor = load_cursor(&path).map_or(0, |c| clamp_cursor(c, lines.len()));
+    Ok(BufferState {
+        file_path: path,
+        lines,
+        cursor,
+        scroll_offset: 0,
+        last_modified: mod_time,
+        undo_stack: Vec::new(),
+        redo_stack: Vec::new(),
+        dirty: false,
+        indent_unit,
+        line_ending,
+    })
+}
+
+fn tab_name(path: &Path) -> String {
+    path.file_name()
+        .and_then(|s| s.to_str())
+        .unwrap_or("todo.md")
+        .to_string()
+}
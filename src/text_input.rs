@@ -1,4 +1,4 @@
-use unicode_width::UnicodeWidthStr;
+use crate::ansi::truncate_visible;
 
 // Minimal text input model for inline editing.
 #[derive(Debug, Clone)]
@@ -19,6 +19,11 @@ impl TextInput {
         &self.value
     }
 
+    // The text before and after the cursor.
+    pub fn split_at_cursor(&self) -> (&str, &str) {
+        self.value.split_at(self.cursor)
+    }
+
     pub fn set_value(&mut self, value: String) {
         self.value = value;
         self.cursor = self.value.len();
@@ -93,13 +98,9 @@ impl TextInput {
         let char_count = content.chars().count();
         let cursor_char_idx = content[..cursor_pos.min(content.len())].chars().count();
 
-        let (start, end) = if char_count <= width {
-            (0, char_count)
-        } else {
-            let start = cursor_char_idx.saturating_sub(width);
-            let end = (start + width).min(char_count);
-            (start, end)
-        };
+        // Scroll just far enough that the cursor cell is the last visible column.
+        let start = (cursor_char_idx + 1).saturating_sub(width);
+        let end = (start + width).min(char_count);
 
         let mut visible = slice_by_char_range(&content, start, end);
         let cursor_in_visible = cursor_char_idx
@@ -108,8 +109,37 @@ impl TextInput {
         let at_end = cursor_char_idx >= char_count;
         visible = apply_block_cursor(&visible, cursor_in_visible, at_end);
 
-        // Ensure width doesn't exceed constraints (best-effort for ASCII + ANSI).
-        trim_to_width(&visible, width)
+        // The block cursor adds escapes, so measure visible columns only.
+        truncate_visible(&visible, width)
+    }
+
+    // Render the input over as many rows of `width` columns as it needs,
+    // rather than scrolling it horizontally.
+    pub fn wrapped_view(&self, placeholder: &str, width: usize) -> Vec<String> {
+        let (content, cursor_pos) = if self.value.is_empty() {
+            (placeholder, 0)
+        } else {
+            (self.value.as_str(), self.cursor)
+        };
+        let width = width.max(1);
+        let chars: Vec<char> = content.chars().collect();
+        let cursor_char_idx = content[..cursor_pos.min(content.len())].chars().count();
+
+        // A cursor past the end of a full row starts a row of its own.
+        let row_count = cursor_char_idx / width + 1;
+        let row_count = row_count.max(chars.len().div_ceil(width));
+        (0..row_count)
+            .map(|row| {
+                let start = row * width;
+                let end = (start + width).min(chars.len());
+                let text: String = chars[start.min(end)..end].iter().collect();
+                if cursor_char_idx / width != row {
+                    return text;
+                }
+                let at_end = cursor_char_idx >= chars.len();
+                apply_block_cursor(&text, cursor_char_idx - start, at_end)
+            })
+            .collect()
     }
 }
 
@@ -158,20 +188,3 @@ fn apply_block_cursor(s: &str, cursor_pos: usize, at_end: bool) -> String {
     }
     out
 }
-
-fn trim_to_width(s: &str, width: usize) -> String {
-    if UnicodeWidthStr::width(s) <= width {
-        return s.to_string();
-    }
-    let mut out = String::new();
-    let mut w = 0;
-    for ch in s.chars() {
-        let cw = UnicodeWidthStr::width(ch.to_string().as_str());
-        if w + cw > width {
-            break;
-        }
-        out.push(ch);
-        w += cw;
-    }
-    out
-}
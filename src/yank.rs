@@ -0,0 +1,262 @@
+use crate::clipboard::copy_to_clipboard;
+use crate::edit::{get_indent_level, indent_for};
+use crate::markdown::plain_text;
+use crate::model::{App, LineItem, Task};
+
+impl App {
+    // Paste the register below (or above) the cursor, re-indenting it to fit.
+    pub fn paste(&mut self, below: bool) {
+        if self.register.is_empty() {
+            self.set_status("Nothing to paste");
+            return;
+        }
+
+        let target_level = match self.lines.get(self.cursor) {
+            Some(LineItem::Task(task)) => get_indent_level(&task.indent, &self.indent_unit),
+            _ => 0,
+        };
+        let mut items = self.register.clone();
+        reindent_block(&mut items, target_level, &self.indent_unit);
+
+        let idx = if self.lines.is_empty() {
+            0
+        } else if below {
+            self.insert_below_index()
+        } else {
+            self.cursor
+        };
+        let count = items.len();
+
+        self.save_undo_state();
+        self.clear_selection();
+        self.lines.splice(idx..idx, items);
+        self.cursor = idx;
+        let msg = if count == 1 {
+            "Pasted 1 line".to_string()
+        } else {
+            format!("Pasted {} lines", count)
+        };
+        self.save_and_set_status(&msg);
+    }
+
+    // `Y`: insert a copy of the current line, or of every selected line,
+    // just below it, with copied tasks reopened. A lone section header is
+    // copied without its tasks, so the copy goes after the section.
+    pub fn duplicate_lines(&mut self) {
+        let indices: Vec<usize> = if self.has_selection() {
+            self.selected_indices()
+        } else {
+            vec![self.cursor]
+        };
+        let copies: Vec<LineItem> = indices
+            .iter()
+            .filter_map(|&i| match self.lines.get(i)? {
+                LineItem::Task(task) => Some(LineItem::Task(Task {
+                    completed: false,
+                    folded: false,
+                    ..task.clone()
+                })),
+                LineItem::Section { title, .. } => Some(LineItem::Section {
+                    title: title.clone(),
+                    collapsed: false,
+                }),
+                LineItem::File { .. } => None,
+                line => Some(line.clone()),
+            })
+            .collect();
+        let Some(&last) = indices.last().filter(|_| !copies.is_empty()) else {
+            self.set_status("Nothing to duplicate");
+            return;
+        };
+        let idx = match &self.lines[last] {
+            LineItem::Section { .. } if indices.len() == 1 => self.section_end(last),
+            LineItem::Task(task) if task.folded => self.task_block_end(last),
+            _ => last + 1,
+        };
+        let count = copies.len();
+
+        self.save_undo_state();
+        self.clear_selection();
+        self.expand_section_containing(idx.saturating_sub(1));
+        self.lines.splice(idx..idx, copies);
+        self.renumber_list(idx);
+        self.cursor = idx;
+        self.remember_edit(idx);
+        let msg = if count == 1 {
+            "Duplicated 1 line".to_string()
+        } else {
+            format!("Duplicated {} lines", count)
+        };
+        self.save_and_set_status(&msg);
+    }
+
+    // Copy the selection, or `count` lines on screen from the cursor, into
+    // the register for `p`/`P`. File headers are never yanked.
+    pub fn yank_lines(&mut self, count: usize) {
+        let indices: Vec<usize> = if self.has_selection() {
+            self.selected_indices()
+        } else {
+            self.count_range(count)
+        };
+        let items: Vec<LineItem> = indices
+            .iter()
+            .map(|&i| self.lines[i].clone())
+            .filter(|line| !line.is_file())
+            .collect();
+        if items.is_empty() {
+            self.set_status("Nothing to yank");
+            return;
+        }
+        self.clear_selection();
+        self.set_status(if items.len() == 1 {
+            "Yanked 1 line".to_string()
+        } else {
+            format!("Yanked {} lines", items.len())
+        });
+        self.register = items;
+    }
+
+    // Copy the current task's text, with markdown formatting stripped.
+    pub fn copy_task_text(&mut self) {
+        let text = match self.lines.get(self.cursor) {
+            Some(LineItem::Task(task)) => plain_text(&task.text),
+            _ => {
+                self.set_status("No task to copy");
+                return;
+            }
+        };
+        self.copy_with_status(&text, "Copied task text");
+    }
+
+    // Copy the current task as a top-level markdown list item, checkbox and
+    // all, ready to paste into another note.
+    pub fn copy_task_markdown(&mut self) {
+        let line = match self.lines.get(self.cursor) {
+            Some(LineItem::Task(task)) => Task {
+                indent: String::new(),
+                ..task.clone()
+            }
+            .line(),
+            _ => {
+                self.set_status("No task to copy");
+                return;
+            }
+        };
+        self.copy_with_status(&line, "Copied task as markdown");
+    }
+
+    // Copy a markdown link to the current task: its text pointing at the file
+    // and the heading of the section it's in, e.g. `[Buy milk](todo.md#errands)`.
+    pub fn copy_task_reference(&mut self) {
+        let Some(LineItem::Task(task)) = self.lines.get(self.cursor) else {
+            self.set_status("No task to copy");
+            return;
+        };
+        let mut file = self.file_path.clone();
+        let mut anchor = None;
+        for line in self.lines[..self.cursor].iter().rev() {
+            match line {
+                LineItem::Section { title, .. } if anchor.is_none() => {
+                    anchor = Some(heading_anchor(title))
+                }
+                LineItem::File { path } => {
+                    file = path.clone();
+                    break;
+                }
+                _ => {}
+            }
+        }
+        let name = file
+            .file_name()
+            .map(|name| name.to_string_lossy().into_owned())
+            .unwrap_or_default();
+        let target = match anchor {
+            Some(anchor) => format!("{}#{}", name, anchor),
+            None => name,
+        };
+        let link = format!("[{}]({})", plain_text(&task.text), target);
+        self.copy_with_status(&link, "Copied task reference");
+    }
+
+    fn copy_with_status(&mut self, text: &str, msg: &str) {
+        match copy_to_clipboard(text) {
+            Ok(()) => self.set_status(msg.to_string()),
+            Err(err) => self.set_status(err),
+        }
+    }
+}
+
+// GitHub-style anchor for a heading: lowercase, punctuation dropped and
+// spaces turned into dashes.
+fn heading_anchor(title: &str) -> String {
+    title
+        .trim()
+        .to_lowercase()
+        .chars()
+        .filter_map(|ch| match ch {
+            ' ' => Some('-'),
+            ch if ch.is_alphanumeric() || ch == '-' || ch == '_' => Some(ch),
+            _ => None,
+        })
+        .collect()
+}
+
+// Shift a block of tasks so its shallowest task sits at `target_level`,
+// keeping the relative nesting of everything below it.
+pub fn reindent_block(items: &mut [LineItem], target_level: usize, unit: &str) {
+    let Some(min_level) = items
+        .iter()
+        .filter_map(|item| match item {
+            LineItem::Task(task) => Some(get_indent_level(&task.indent, unit)),
+            _ => None,
+        })
+        .min()
+    else {
+        return;
+    };
+
+    for item in items.iter_mut() {
+        if let LineItem::Task(task) = item {
+            let level = get_indent_level(&task.indent, unit) - min_level + target_level;
+            task.indent = indent_for(level, unit);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::io::{parse_lines, serialize_lines, LineEnding};
+
+    fn reindented(block: &str, target_level: usize) -> String {
+        let mut items = parse_lines(block);
+        reindent_block(&mut items, target_level, "  ");
+        serialize_lines(&items, LineEnding::Lf)
+    }
+
+    const BLOCK: &str = "  - [ ] parent\n    - [ ] child\n      - [ ] grandchild\n";
+
+    #[test]
+    fn reindent_to_top_level() {
+        assert_eq!(
+            reindented(BLOCK, 0),
+            "- [ ] parent\n  - [ ] child\n    - [ ] grandchild\n"
+        );
+    }
+
+    #[test]
+    fn reindent_under_a_subtask() {
+        assert_eq!(
+            reindented("- [ ] parent\n  - [ ] child\n", 1),
+            "  - [ ] parent\n    - [ ] child\n"
+        );
+    }
+
+    #[test]
+    fn reindent_clamps_at_max_level() {
+        assert_eq!(
+            reindented(BLOCK, 3),
+            "      - [ ] parent\n      - [ ] child\n      - [ ] grandchild\n"
+        );
+    }
+}